@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"strconv"
@@ -8,8 +9,12 @@ import (
 
 	"runbin/internal/config"
 	"runbin/internal/controller"
+	"runbin/internal/middleware"
+	"runbin/internal/notify"
 	"runbin/internal/repository"
+	"runbin/internal/retention"
 	"runbin/internal/router"
+	"runbin/internal/webhook"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -25,21 +30,94 @@ func main() {
 
 	// Initialize storage
 	var store repository.PasteRepository
+	var users repository.UserRepository
+	var feed repository.ChangeFeed
+	var webhooks repository.WebhookStore
+	var dbStore *repository.PostgresStore
 	switch cfg.Storage.Type {
 	case "memory":
-		store = repository.NewMemoryPasteStore()
+		memStore := repository.NewMemoryPasteStore()
+		store = memStore
+		users = memStore
+		feed = memStore
+		webhooks = memStore
 	case "database":
-		dbStore, err := repository.NewPostgresStore(cfg.Storage.Database.DSN)
+		var err error
+		dbStore, err = repository.NewPostgresStore(cfg.Storage.Database.DSN)
 		defer dbStore.Close()
 		if err != nil {
 			log.Fatalf("Failed to connect to database: %v", err)
 		}
 		store = dbStore
+		users = dbStore
+		feed = dbStore
+		webhooks = dbStore
 	default:
 		log.Fatalf("Unsupported storage type: %s", cfg.Storage.Type)
 	}
 
-	pasteHandler := controller.NewPasteHandler(store)
+	// Rate limit buckets live in the database by default so that replicas
+	// share them
+	var limits repository.RateLimitStore
+	if cfg.RateLimit.Enabled {
+		limitStore := cfg.RateLimit.Store
+		if limitStore == "" {
+			limitStore = cfg.Storage.Type
+		}
+		switch {
+		case limitStore == "memory":
+			limits = repository.NewMemoryRateLimitStore()
+		case limitStore == "database" && dbStore != nil:
+			limits = dbStore
+		default:
+			log.Fatalf("Unsupported rate limit store: %s", limitStore)
+		}
+	}
+
+	// Create the first admin so that further users can be created
+	if cfg.Auth.BootstrapAdmin != "" {
+		key, err := controller.BootstrapAdmin(users, cfg.Auth.BootstrapAdmin)
+		if err != nil {
+			log.Fatalf("Failed to create admin user: %v", err)
+		}
+		if key != "" {
+			log.Printf("Created admin user %q with API key %s", cfg.Auth.BootstrapAdmin, key)
+		}
+	}
+
+	// Requests waiting for a result are woken by the repository's change
+	// notifications instead of polling it
+	hub := notify.NewHub()
+	go func() {
+		if err := feed.WatchPastes(context.Background(), hub.Publish); err != nil {
+			log.Printf("Paste change feed error: %v", err)
+		}
+	}()
+
+	pasteHandler := controller.NewPasteHandler(store, hub, cfg)
+	userHandler := controller.NewUserHandler(users, store)
+
+	var oidcHandler *controller.OIDCHandler
+	if cfg.Auth.OIDC.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		h, err := controller.NewOIDCHandler(ctx, users, &cfg.Auth.OIDC)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to set up OIDC login: %v", err)
+		}
+		oidcHandler = h
+	}
+
+	// Deliver the callbacks of finished pastes
+	if len(cfg.Webhook.AllowedHosts) > 0 {
+		if cfg.Webhook.Secret == "" {
+			log.Fatalf("webhook.secret must be set when callbacks are allowed")
+		}
+		go webhook.NewDispatcher(store, webhooks, &cfg.Webhook).Run(context.Background())
+	}
+
+	// Delete expired pastes and sessions in the background
+	go retention.NewReaper(store, users, limits, cfg).Run(context.Background())
 
 	// Create router engine
 	engine := gin.Default()
@@ -47,17 +125,17 @@ func main() {
 	// Add CORS middleware
 	engine.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Management-Token", "X-Paste-Password"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-Management-Token"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 	engine.Use(func(c *gin.Context) {
 		if c.Request.Method == http.MethodOptions {
 			c.Header("Access-Control-Allow-Origin", "*")
-			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Management-Token, X-Paste-Password")
 			c.Header("Access-Control-Allow-Headers", "86400")
 			c.AbortWithStatus(http.StatusOK)
 			return
@@ -68,7 +146,17 @@ func main() {
 	engine.SetTrustedProxies(nil)
 
 	// Setup routes
-	router.SetupRoutes(engine, pasteHandler)
+	handlers := router.Handlers{
+		Paste:        pasteHandler,
+		User:         userHandler,
+		OIDC:         oidcHandler,
+		Authenticate: middleware.Authenticate(users),
+		MaxBodySize:  middleware.MaxBodySize(int64(cfg.Submission.MaxBodySize)),
+	}
+	if limits != nil {
+		handlers.RateLimit = middleware.RateLimit(limits, &cfg.RateLimit)
+	}
+	router.SetupRoutes(engine, handlers)
 
 	// Configure Gin mode based on environment
 	if cfg.App.Env == "release" {
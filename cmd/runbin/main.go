@@ -0,0 +1,269 @@
+// Command runbin is the command-line client of RunBin.
+//
+//	runbin [-server url] [-key api_key] <command> [arguments]
+//
+// The server and API key default to the RUNBIN_SERVER and RUNBIN_API_KEY
+// environment variables.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"runbin/internal/model"
+	"runbin/pkg/client"
+)
+
+const (
+	// Exit status when runbin itself fails rather than the program, as used
+	// by docker run
+	exitFailure = 125
+	exitUsage   = 2
+
+	pollInterval = 500 * time.Millisecond
+)
+
+const usage = `Usage: runbin [-server url] [-key api_key] <command> [arguments]
+
+Commands:
+  run [-lang l] [-stdin file] [-timeout d] [-no-wait] <file>
+        submit a file, wait for it to finish, print its output and exit
+        with its exit status; file "-" reads the code from standard input
+  get <id>
+        print a paste as JSON
+  raw <id> [stdout|stderr|compile_log]
+        print the source or an output of a paste
+  fork [-code file] [-lang l] [-stdin file] [-run] <id>
+        create a new revision of a paste and print its ID
+  languages
+        list the supported languages
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := flag.String("server", envOr("RUNBIN_SERVER", "http://localhost:8080"), "RunBin server URL")
+	apiKey := flag.String("key", os.Getenv("RUNBIN_API_KEY"), "API key")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	c := client.New(*server, client.WithAPIKey(*apiKey))
+	ctx := context.Background()
+	args := flag.Args()[1:]
+
+	var err error
+	switch flag.Arg(0) {
+	case "run":
+		var code int
+		code, err = runCommand(ctx, c, args)
+		if err == nil {
+			os.Exit(code)
+		}
+	case "get":
+		err = getCommand(ctx, c, args)
+	case "raw":
+		err = rawCommand(ctx, c, args)
+	case "fork":
+		err = forkCommand(ctx, c, args)
+	case "languages":
+		err = languagesCommand(ctx, c, args)
+	default:
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	if err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "runbin %s: %v\n", flag.Arg(0), err)
+			os.Exit(exitUsage)
+		}
+		fmt.Fprintf(os.Stderr, "runbin: %s\n", strings.TrimPrefix(err.Error(), "runbin: "))
+		os.Exit(exitFailure)
+	}
+}
+
+// usageError is returned for invalid command-line arguments.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// runCommand submits a file and waits for its result. It returns the exit
+// status of the program, or exitFailure if the program did not exit by
+// itself.
+func runCommand(ctx context.Context, c *client.Client, args []string) (int, error) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	lang := fs.String("lang", "", "language; inferred from the file extension if omitted")
+	stdinFile := fs.String("stdin", "", "file to use as the program's standard input")
+	timeout := fs.Duration("timeout", 5*time.Minute, "how long to wait for the result; 0 waits indefinitely")
+	noWait := fs.Bool("no-wait", false, "print the paste ID instead of waiting for the result")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return 0, usageError("expects exactly one file")
+	}
+
+	file := fs.Arg(0)
+	if *lang == "" {
+		l, ok := model.LookupExtension(file)
+		if !ok {
+			return 0, usageError("cannot infer the language of " + file + ", set -lang")
+		}
+		*lang = l.Name
+	}
+	code, err := readInput(file)
+	if err != nil {
+		return 0, err
+	}
+	var stdin string
+	if *stdinFile != "" {
+		if stdin, err = readInput(*stdinFile); err != nil {
+			return 0, err
+		}
+	}
+
+	created, err := c.Submit(ctx, &client.SubmitRequest{
+		Code:     code,
+		Language: *lang,
+		Stdin:    stdin,
+		Run:      true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if *noWait {
+		fmt.Println(created.ID)
+		return 0, nil
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	paste, err := c.Wait(ctx, created.ID, pollInterval)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return 0, fmt.Errorf("paste %s not finished after %s", created.ID, *timeout)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Fprint(os.Stdout, paste.Result.Stdout)
+	fmt.Fprint(os.Stderr, paste.Result.Stderr)
+	if paste.Status == client.StatusCompileError {
+		fmt.Fprint(os.Stderr, paste.Result.CompileLog)
+	}
+	if paste.Result.ExitCode != nil {
+		return *paste.Result.ExitCode, nil
+	}
+	fmt.Fprintf(os.Stderr, "runbin: %s\n", paste.Status)
+	return exitFailure, nil
+}
+
+func getCommand(ctx context.Context, c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return usageError("expects a paste ID")
+	}
+	paste, err := c.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(paste)
+}
+
+func rawCommand(ctx context.Context, c *client.Client, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return usageError("expects a paste ID and an optional stream")
+	}
+	var stream string
+	if len(args) == 2 {
+		stream = args[1]
+	}
+	text, err := c.Raw(ctx, args[0], stream)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, text)
+	return err
+}
+
+func forkCommand(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("fork", flag.ExitOnError)
+	codeFile := fs.String("code", "", "file with the new code")
+	lang := fs.String("lang", "", "new language")
+	stdinFile := fs.String("stdin", "", "file with the new standard input")
+	run := fs.Bool("run", false, "run the new revision")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError("expects a paste ID")
+	}
+
+	req := client.ForkRequest{Run: *run}
+	if *codeFile != "" {
+		code, err := readInput(*codeFile)
+		if err != nil {
+			return err
+		}
+		req.Code = &code
+	}
+	if *lang != "" {
+		req.Language = lang
+	}
+	if *stdinFile != "" {
+		stdin, err := readInput(*stdinFile)
+		if err != nil {
+			return err
+		}
+		req.Stdin = &stdin
+	}
+
+	created, err := c.Fork(ctx, fs.Arg(0), &req)
+	if err != nil {
+		return err
+	}
+	fmt.Println(created.ID)
+	return nil
+}
+
+func languagesCommand(ctx context.Context, c *client.Client, args []string) error {
+	if len(args) != 0 {
+		return usageError("takes no arguments")
+	}
+	languages, err := c.Languages(ctx)
+	if err != nil {
+		return err
+	}
+	for _, lang := range languages {
+		fmt.Println(lang.Name)
+	}
+	return nil
+}
+
+// readInput reads a file, or standard input for "-".
+func readInput(name string) (string, error) {
+	if name == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(name)
+	return string(data), err
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,64 @@
+// Package apierror writes error responses in the format shared by all API
+// endpoints:
+//
+//	{"error": {"code": "not_found", "message": "Paste not found"}}
+//
+// The code is stable and meant for programs; the message is for people and
+// may change.
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Code string
+
+const (
+	CodeInvalidRequest      Code = "invalid_request"
+	CodePayloadTooLarge     Code = "payload_too_large"
+	CodeUnsupportedLanguage Code = "unsupported_language"
+	CodeCodeTooLarge        Code = "code_too_large"
+	CodeStdinTooLarge       Code = "stdin_too_large"
+	CodeInvalidEncoding     Code = "invalid_encoding"
+	CodeCallbackNotAllowed  Code = "callback_not_allowed"
+	CodeBatchTooLarge       Code = "batch_too_large"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeInvalidAPIKey       Code = "invalid_api_key"
+	CodePasswordRequired    Code = "password_required"
+	CodeForbidden           Code = "forbidden"
+	CodeInsufficientScope   Code = "insufficient_scope"
+	CodeInvalidPassword     Code = "invalid_password"
+	CodeNotFound            Code = "not_found"
+	CodeExpired             Code = "expired"
+	CodeRateLimited         Code = "rate_limited"
+	CodeQuotaExceeded       Code = "quota_exceeded"
+	CodeInternal            Code = "internal_error"
+)
+
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New returns an error carrying a code, for helpers that leave writing the
+// response to their caller.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Respond aborts the request with status and an error body.
+func Respond(c *gin.Context, status int, code Code, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": Error{Code: code, Message: message}})
+}
+
+// Internal aborts the request with a 500 response. The cause is left to the
+// caller to log, as it must not be shown to clients.
+func Internal(c *gin.Context) {
+	Respond(c, http.StatusInternalServerError, CodeInternal, "Internal Server Error")
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewToken returns a random secret token starting with prefix together with
+// the hash to store in its place.
+func NewToken(prefix string) (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generate token error: %v", err)
+	}
+	token = prefix + hex.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a token for storage. Tokens carry 256 bits of entropy,
+// so a plain SHA-256 is enough and allows lookups by hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenMatches reports whether token hashes to hash, in constant time.
+func TokenMatches(token, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
@@ -1,14 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/spf13/viper"
 )
 
 type AppConfig struct {
-	Env  string
-	Port int
+	Env     string
+	Port    int
+	WebURL  string
+	MaxWait int
 }
 
 type DatabaseConfig struct {
@@ -20,20 +23,110 @@ type StorageConfig struct {
 	Database DatabaseConfig
 }
 
+type DedupConfig struct {
+	Enabled bool
+	Window  int
+}
+
+type RetentionConfig struct {
+	MaxAge   int
+	Interval int
+}
+
+type OIDCConfig struct {
+	Enabled        bool
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	AllowedDomains []string
+	SessionTTL     int
+	SecureCookie   bool
+	LoginRedirect  string
+}
+
+type AuthConfig struct {
+	BootstrapAdmin string
+	OIDC           OIDCConfig
+}
+
+type BucketConfig struct {
+	Rate  float64
+	Burst int
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string
+	IP      BucketConfig
+	Key     BucketConfig
+}
+
+type QuotaConfig struct {
+	CPUSeconds int
+}
+
+type SubmissionConfig struct {
+	MaxCodeSize  int
+	MaxStdinSize int
+	MaxBodySize  int
+	MaxBatchSize int
+}
+
+type WebhookConfig struct {
+	AllowedHosts []string
+	Secret       string
+	MaxAttempts  int
+	Timeout      int
+}
+
 type ApiConfig struct {
-	App     AppConfig
-	Storage StorageConfig
+	App        AppConfig
+	Storage    StorageConfig
+	Dedup      DedupConfig
+	Retention  RetentionConfig
+	Auth       AuthConfig
+	RateLimit  RateLimitConfig
+	Quota      QuotaConfig
+	Submission SubmissionConfig
+	Webhook    WebhookConfig
 }
 
 func LoadApi(configFile string) *ApiConfig {
 	v := viper.New()
 	v.SetConfigFile(configFile)
 	v.SetConfigType("yaml")
-	
+
 	// Set default values
 	v.SetDefault("app.env", "debug")
 	v.SetDefault("app.port", 8080)
+	v.SetDefault("app.weburl", "")
+	v.SetDefault("app.maxwait", 60)
 	v.SetDefault("storage.type", "memory")
+	v.SetDefault("dedup.enabled", false)
+	v.SetDefault("dedup.window", 600)
+	v.SetDefault("retention.maxage", 0)
+	v.SetDefault("retention.interval", 300)
+	v.SetDefault("auth.bootstrapadmin", "")
+	v.SetDefault("auth.oidc.enabled", false)
+	v.SetDefault("auth.oidc.sessionttl", 604800)
+	v.SetDefault("auth.oidc.securecookie", true)
+	v.SetDefault("auth.oidc.loginredirect", "/")
+	v.SetDefault("ratelimit.enabled", false)
+	v.SetDefault("ratelimit.store", "")
+	v.SetDefault("ratelimit.ip.rate", 0.1)
+	v.SetDefault("ratelimit.ip.burst", 10)
+	v.SetDefault("ratelimit.key.rate", 1)
+	v.SetDefault("ratelimit.key.burst", 30)
+	v.SetDefault("quota.cpuseconds", 0)
+	v.SetDefault("submission.maxcodesize", 65536)
+	v.SetDefault("submission.maxstdinsize", 1048576)
+	v.SetDefault("submission.maxbodysize", 4194304)
+	v.SetDefault("submission.maxbatchsize", 100)
+	v.SetDefault("webhook.allowedhosts", []string{})
+	v.SetDefault("webhook.secret", "")
+	v.SetDefault("webhook.maxattempts", 8)
+	v.SetDefault("webhook.timeout", 10)
 
 	if err := v.ReadInConfig(); err != nil {
 		log.Fatalf("Failed to read config file: %v", err)
@@ -44,5 +137,29 @@ func LoadApi(configFile string) *ApiConfig {
 		log.Fatalf("Failed to unmarshal config: %v", err)
 	}
 
+	// The retention sweep also deletes expired sessions and idle rate limit
+	// buckets, so it cannot be turned off
+	if cfg.Retention.Interval <= 0 {
+		log.Fatalf("Invalid retention.interval: must be positive, got %d", cfg.Retention.Interval)
+	}
+
+	if cfg.RateLimit.Enabled {
+		for name, bucket := range map[string]BucketConfig{"ip": cfg.RateLimit.IP, "key": cfg.RateLimit.Key} {
+			if err := bucket.validate(); err != nil {
+				log.Fatalf("Invalid ratelimit.%s config: %v", name, err)
+			}
+		}
+	}
+
 	return &cfg
 }
+
+func (b BucketConfig) validate() error {
+	if b.Rate <= 0 {
+		return fmt.Errorf("rate must be positive, got %v", b.Rate)
+	}
+	if b.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1, got %d", b.Burst)
+	}
+	return nil
+}
@@ -7,15 +7,40 @@ import (
 )
 
 type LimitConfig struct {
-	Cpu    float32
-	Memory int
-	Time   float32
-	Size   int
+	Cpu      float32
+	Memory   int
+	Time     float32
+	Size     int
+	Output   int
+	Pids     int
+	FileSize int
+	NoFile   int
+	Tmpfs    int
+}
+
+type SecurityConfig struct {
+	User           string
+	SeccompProfile string
+	Runtime        string
+}
+
+type ReaperConfig struct {
+	Interval int
+	MaxAge   int
+}
+
+type CacheConfig struct {
+	Enabled bool
+	Dir     string
+	MaxSize int
 }
 
 type WorkerConfig struct {
 	Storage       StorageConfig
 	Limit         LimitConfig
+	Security      SecurityConfig
+	Reaper        ReaperConfig
+	Cache         CacheConfig
 	Process       int
 	Name          string
 	CompilerImage string
@@ -30,6 +55,19 @@ func LoadWorker(configFile string) *WorkerConfig {
 	v.SetDefault("limit.time", 10.0)
 	v.SetDefault("limit.memory", 512*1024)
 	v.SetDefault("limit.size", 1024)
+	v.SetDefault("limit.output", 16*1024*1024)
+	v.SetDefault("limit.pids", 64)
+	v.SetDefault("limit.filesize", 64*1024*1024)
+	v.SetDefault("limit.nofile", 64)
+	v.SetDefault("limit.tmpfs", 64)
+	v.SetDefault("security.user", "65534:65534")
+	v.SetDefault("security.seccompprofile", "workerEnv/seccomp.json")
+	v.SetDefault("security.runtime", "")
+	v.SetDefault("reaper.interval", 60)
+	v.SetDefault("reaper.maxage", 600)
+	v.SetDefault("cache.enabled", false)
+	v.SetDefault("cache.dir", "/var/cache/runbin")
+	v.SetDefault("cache.maxsize", 1024)
 	v.SetDefault("process", 1)
 	v.SetDefault("name", "default name")
 	v.SetDefault("compilerimage", "cpp_gcc-latest:latest")
@@ -43,5 +81,12 @@ func LoadWorker(configFile string) *WorkerConfig {
 		log.Fatalf("Failed to unmarshal config: %v", err)
 	}
 
+	// A task compiles and then runs, each for at most limit.time. Replicas
+	// sharing a name sweep each other's leftovers, so maxage must cover
+	// a whole task for none of them to remove one still in progress.
+	if taskTime := 2 * cfg.Limit.Time; float32(cfg.Reaper.MaxAge) <= taskTime {
+		log.Fatalf("Invalid reaper.maxage: must exceed twice limit.time (%vs), got %d", taskTime, cfg.Reaper.MaxAge)
+	}
+
 	return &cfg
 }
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/dto"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateBatch submits several pastes at once. Each item is validated like a
+// single submission and the first invalid one rejects the whole batch. The
+// pastes are stored and their runs queued in one transaction, so either all
+// of them run or none do.
+func (h *PasteHandler) CreateBatch(c *gin.Context) {
+	var req model.BatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Items) == 0 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, "A batch needs at least one item")
+		return
+	}
+	if limit := h.cfg.Submission.MaxBatchSize; limit > 0 && len(req.Items) > limit {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeBatchTooLarge,
+			fmt.Sprintf("A batch may have at most %d items", limit))
+		return
+	}
+
+	pastes := make([]*model.Paste, len(req.Items))
+	for i, item := range req.Items {
+		paste, ok := h.batchPaste(c, i, &item)
+		if !ok {
+			return
+		}
+		pastes[i] = paste
+	}
+
+	// Every run is charged to the quota and the rate limit, of which the
+	// request itself already took one token
+	queued := 0
+	for _, item := range req.Items {
+		if item.Run {
+			queued++
+		}
+	}
+	if queued > 0 && !h.withinQuota(c, queued) {
+		return
+	}
+	if !middleware.TakeTokens(c, queued-1) {
+		return
+	}
+
+	batch := &model.Batch{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+	}
+	if key, ok := middleware.CurrentKey(c); ok {
+		batch.OwnerID = key.UserID
+	}
+
+	created := make([]*dto.Created, len(pastes))
+	var runs []*model.Execution
+	for i, paste := range pastes {
+		token, ok := h.preparePaste(c, paste, req.Items[i].Run)
+		if !ok {
+			return
+		}
+		if req.Items[i].Run {
+			runs = append(runs, initialExecution(paste))
+		}
+		created[i] = &dto.Created{
+			ID:              paste.ID,
+			URL:             dto.PasteURL(paste.ID),
+			ManagementToken: token,
+			CachedFrom:      paste.CachedFrom,
+		}
+	}
+
+	if err := h.repo.SaveBatch(batch, pastes, runs); err != nil {
+		apierror.Internal(c)
+		log.Printf("Batch save error: %v", err)
+		return
+	}
+
+	resp := &dto.BatchCreated{
+		ID:    batch.ID,
+		URL:   dto.BatchURL(batch.ID),
+		Items: created,
+	}
+	respond(c, http.StatusAccepted, resp, resp)
+}
+
+// batchPaste validates item i of a batch and returns the paste it
+// describes. Errors name the item they are about.
+func (h *PasteHandler) batchPaste(c *gin.Context, i int, item *model.SubmitRequest) (*model.Paste, bool) {
+	if err := h.sourceError(item.Code, item.Language, item.Stdin); err != nil {
+		apierror.Respond(c, http.StatusUnprocessableEntity, err.Code, fmt.Sprintf("items[%d]: %s", i, err.Message))
+		return nil, false
+	}
+	if status, err := h.callbackError(item.CallbackURL, item.Run); err != nil {
+		apierror.Respond(c, status, err.Code, fmt.Sprintf("items[%d]: %s", i, err.Message))
+		return nil, false
+	}
+
+	expiresAt, err := h.expiry(item.ExpiresIn, item.ExpiresAt)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, fmt.Sprintf("items[%d]: %v", i, err))
+		return nil, false
+	}
+
+	paste := newPaste(item.Code, item.Language, item.Stdin)
+	paste.ExpiresAt = expiresAt
+	paste.BurnAfterReading = item.BurnAfterReading
+	paste.CallbackURL = item.CallbackURL
+	if err := setVisibility(paste, item.Visibility, item.Password); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, fmt.Sprintf("items[%d]: %v", i, err))
+		return nil, false
+	}
+	return paste, true
+}
+
+// GetBatch reports the progress of a batch and the status of each of its
+// pastes. A batch submitted with an API key can only be followed by its
+// owner; one submitted anonymously by anyone who knows its ID.
+func (h *PasteHandler) GetBatch(c *gin.Context) {
+	batch, exists := h.repo.GetBatch(c.Param("id"))
+	if !exists || !ownsBatch(c, batch) {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Batch not found")
+		return
+	}
+
+	pastes, err := h.repo.GetBatchPastes(batch.ID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Batch pastes error: %v", err)
+		return
+	}
+
+	// Expired pastes linger until the retention sweep deletes them
+	now := time.Now()
+	pastes = slices.DeleteFunc(pastes, func(p *model.Paste) bool { return p.IsExpired(now) })
+
+	resp := dto.NewBatch(batch, pastes)
+	respond(c, http.StatusOK, resp, resp)
+}
+
+// ownsBatch reports whether the request may see batch. Admin keys see every
+// batch.
+func ownsBatch(c *gin.Context, batch *model.Batch) bool {
+	if batch.OwnerID == "" {
+		return true
+	}
+	key, ok := middleware.CurrentKey(c)
+	return ok && (key.HasScope(model.ScopeAdmin) || key.UserID == batch.OwnerID)
+}
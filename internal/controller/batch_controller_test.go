@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"runbin/internal/apierror"
+	"runbin/internal/auth"
+	"runbin/internal/config"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+	"runbin/internal/notify"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAPIKey = "rb_test"
+
+// newBatchEngine serves POST /batches with rate limiting and a daily quota
+// of ten CPU seconds, of which the user of testAPIKey has used usedMs.
+func newBatchEngine(t *testing.T, bucket config.BucketConfig, usedMs int) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.ApiConfig{
+		Quota: config.QuotaConfig{CPUSeconds: 10},
+		Submission: config.SubmissionConfig{
+			MaxCodeSize:  65536,
+			MaxStdinSize: 65536,
+			MaxBatchSize: 100,
+		},
+		RateLimit: config.RateLimitConfig{Enabled: true, IP: bucket, Key: bucket},
+	}
+	store := repository.NewMemoryPasteStore()
+	if err := store.SaveAPIKey(&model.APIKey{
+		ID:      "key-1",
+		UserID:  "user-1",
+		KeyHash: auth.HashToken(testAPIKey),
+		Scopes:  []model.Scope{model.ScopeSubmit},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if usedMs > 0 {
+		err := store.UpdateExecution(&model.Execution{
+			ID:              "earlier-run",
+			Status:          model.StatusCompleted,
+			ExecutionTimeMs: usedMs,
+			RequestedBy:     "user-1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewPasteHandler(store, notify.NewHub(), cfg)
+	engine := gin.New()
+	engine.POST("/batches",
+		middleware.Authenticate(store),
+		middleware.RateLimit(repository.NewMemoryRateLimitStore(), &cfg.RateLimit),
+		h.CreateBatch)
+	return engine
+}
+
+// postBatch submits a batch of runs items that run and idle items that do
+// not, and returns the status and error code of the response.
+func postBatch(t *testing.T, engine *gin.Engine, apiKey string, runs, idle int) (int, apierror.Code) {
+	t.Helper()
+	var req model.BatchRequest
+	for i := 0; i < runs+idle; i++ {
+		req.Items = append(req.Items, model.SubmitRequest{
+			Code:     "int main() {}",
+			Language: "c++20",
+			Run:      i < runs,
+		})
+	}
+	body, _ := json.Marshal(req)
+
+	r := httptest.NewRequest(http.MethodPost, "/batches", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		r.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, r)
+
+	var resp struct {
+		Error struct {
+			Code apierror.Code `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp.Error.Code
+}
+
+func TestCreateBatchRateLimit(t *testing.T) {
+	// Three tokens that practically never refill
+	bucket := config.BucketConfig{Rate: 0.001, Burst: 3}
+
+	tests := []struct {
+		name string
+		// Batches submitted one after another, as {runs, idle}
+		batches [][2]int
+		want    int
+		code    apierror.Code
+	}{
+		{
+			name:    "runs within the burst",
+			batches: [][2]int{{3, 5}},
+			want:    http.StatusAccepted,
+		},
+		{
+			name:    "idle items take only the request's token",
+			batches: [][2]int{{0, 5}, {0, 5}, {0, 5}},
+			want:    http.StatusAccepted,
+		},
+		{
+			name:    "runs beyond the tokens left",
+			batches: [][2]int{{2, 0}, {2, 0}},
+			want:    http.StatusTooManyRequests,
+			code:    apierror.CodeRateLimited,
+		},
+		{
+			name:    "runs beyond the burst",
+			batches: [][2]int{{4, 0}},
+			want:    http.StatusUnprocessableEntity,
+			code:    apierror.CodeRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newBatchEngine(t, bucket, 0)
+			var status int
+			var code apierror.Code
+			for i, batch := range tt.batches {
+				status, code = postBatch(t, engine, "", batch[0], batch[1])
+				if i < len(tt.batches)-1 && status != http.StatusAccepted {
+					t.Fatalf("batch %d status = %d, want %d", i, status, http.StatusAccepted)
+				}
+			}
+			if status != tt.want || code != tt.code {
+				t.Errorf("status = %d %q, want %d %q", status, code, tt.want, tt.code)
+			}
+		})
+	}
+}
+
+func TestCreateBatchQuota(t *testing.T) {
+	bucket := config.BucketConfig{Rate: 1, Burst: 100}
+
+	tests := []struct {
+		name   string
+		usedMs int
+		runs   int
+		idle   int
+		want   int
+	}{
+		{name: "no quota used", runs: 10, want: http.StatusAccepted},
+		{name: "a second per run", usedMs: 7000, runs: 3, want: http.StatusAccepted},
+		{name: "remainder rounded up", usedMs: 8500, runs: 2, idle: 5, want: http.StatusAccepted},
+		{name: "too little left", usedMs: 8500, runs: 3, want: http.StatusTooManyRequests},
+		{name: "used up", usedMs: 10000, runs: 1, want: http.StatusTooManyRequests},
+		{name: "used up without runs", usedMs: 10000, idle: 3, want: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newBatchEngine(t, bucket, tt.usedMs)
+			status, code := postBatch(t, engine, testAPIKey, tt.runs, tt.idle)
+			if status != tt.want {
+				t.Fatalf("status = %d %q, want %d", status, code, tt.want)
+			}
+			if status == http.StatusTooManyRequests && code != apierror.CodeQuotaExceeded {
+				t.Errorf("code = %q, want %q", code, apierror.CodeQuotaExceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/auth"
+	"runbin/internal/config"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+	"runbin/internal/repository"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Cookie carrying the state, nonce and PKCE verifier of a login in
+	// progress, separated by colons
+	loginCookie       = "runbin_login"
+	loginCookieMaxAge = 600
+
+	// Prefix of session tokens, so they are recognisable when leaked.
+	sessionTokenPrefix = "rbs_"
+)
+
+// OIDCHandler signs users in through an OpenID Connect provider using the
+// authorization code flow with PKCE.
+type OIDCHandler struct {
+	users    repository.UserRepository
+	cfg      *config.OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCHandler discovers the provider configured in cfg.
+func NewOIDCHandler(ctx context.Context, users repository.UserRepository, cfg *config.OIDCConfig) (*OIDCHandler, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover OIDC provider %s error: %v", cfg.Issuer, err)
+	}
+
+	return &OIDCHandler{
+		users: users,
+		cfg:   cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// Login redirects the browser to the provider.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	state := oauth2.GenerateVerifier()
+	nonce := oauth2.GenerateVerifier()
+	verifier := oauth2.GenerateVerifier()
+
+	h.setCookie(c, loginCookie, strings.Join([]string{state, nonce, verifier}, ":"), loginCookieMaxAge)
+	c.Redirect(http.StatusFound, h.oauth2.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(verifier)))
+}
+
+// Callback completes a login. It maps the identity to a RunBin user,
+// creating one on first sign-in, and starts a session.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if reason := c.Query("error"); reason != "" {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Login failed: "+reason)
+		return
+	}
+
+	value, _ := c.Cookie(loginCookie)
+	h.setCookie(c, loginCookie, "", -1)
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(c.Query("state"))) != 1 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid login state")
+		return
+	}
+	nonce, verifier := parts[1], parts[2]
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	token, err := h.oauth2.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Login failed")
+		log.Printf("OIDC code exchange error: %v", err)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Login failed")
+		log.Printf("OIDC token response has no id_token")
+		return
+	}
+	idToken, err := h.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Login failed")
+		log.Printf("OIDC id_token verify error: %v", err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Login failed")
+		log.Printf("OIDC id_token nonce mismatch")
+		return
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     *bool  `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Login failed")
+		log.Printf("OIDC claims error: %v", err)
+		return
+	}
+	if !h.domainAllowed(claims.Email, claims.EmailVerified) {
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Email domain not allowed")
+		return
+	}
+
+	user, found := h.users.GetUserByIdentity(idToken.Issuer, idToken.Subject)
+	if !found {
+		name := claims.Name
+		if name == "" {
+			name = claims.PreferredUsername
+		}
+		if name == "" {
+			name = claims.Email
+		}
+		user = &model.User{
+			ID:          uuid.NewString(),
+			Name:        name,
+			Email:       claims.Email,
+			CreatedAt:   time.Now(),
+			OIDCIssuer:  idToken.Issuer,
+			OIDCSubject: idToken.Subject,
+		}
+		if err := h.users.SaveUser(user); err != nil {
+			apierror.Internal(c)
+			log.Printf("User create error: %v", err)
+			return
+		}
+	}
+
+	sessionToken, sessionHash, err := auth.NewToken(sessionTokenPrefix)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Session token error: %v", err)
+		return
+	}
+	now := time.Now()
+	session := &model.Session{
+		TokenHash: sessionHash,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(h.cfg.SessionTTL) * time.Second),
+	}
+	if err := h.users.SaveSession(session); err != nil {
+		apierror.Internal(c)
+		log.Printf("Session save error: %v", err)
+		return
+	}
+
+	h.setCookie(c, middleware.SessionCookie, sessionToken, h.cfg.SessionTTL)
+	c.Redirect(http.StatusFound, h.cfg.LoginRedirect)
+}
+
+// Logout ends the browser session.
+func (h *OIDCHandler) Logout(c *gin.Context) {
+	if token, err := c.Cookie(middleware.SessionCookie); err == nil && token != "" {
+		if err := h.users.DeleteSession(auth.HashToken(token)); err != nil {
+			apierror.Internal(c)
+			log.Printf("Session delete error: %v", err)
+			return
+		}
+	}
+	h.setCookie(c, middleware.SessionCookie, "", -1)
+	c.Status(http.StatusNoContent)
+}
+
+// domainAllowed reports whether an email address may sign in. With no
+// allowed domains configured, anyone the provider authenticates may.
+// Otherwise the provider must vouch for the address with email_verified, as
+// some let users pick their own.
+func (h *OIDCHandler) domainAllowed(email string, verified *bool) bool {
+	if len(h.cfg.AllowedDomains) == 0 {
+		return true
+	}
+	if verified == nil || !*verified {
+		return false
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	return slices.ContainsFunc(h.cfg.AllowedDomains, func(allowed string) bool {
+		return strings.ToLower(allowed) == domain
+	})
+}
+
+// setCookie sets an HTTP-only cookie on the API path. SameSite=Lax keeps the
+// cookie on the top-level redirect back from the provider while withholding
+// it from cross-site requests that change state.
+func (h *OIDCHandler) setCookie(c *gin.Context, name, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, "/api", "", h.cfg.SecureCookie, true)
+}
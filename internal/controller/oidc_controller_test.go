@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"runbin/internal/config"
+	"runbin/internal/middleware"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testClientID = "runbin"
+
+// mockProvider is an OpenID Connect provider serving discovery, JWKS and a
+// token endpoint that checks the PKCE verifier. Authorization is skipped:
+// authorize registers a code for the parameters of a login redirect.
+type mockProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorization
+	// Claims added to every ID token
+	claims map[string]any
+	// Nonce put in ID tokens instead of the requested one, if set
+	nonce string
+}
+
+type authorization struct {
+	nonce     string
+	challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key, codes: make(map[string]authorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   b64(key.N.Bytes()),
+			"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	auth, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || b64(sum[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := auth.nonce
+	if p.nonce != "" {
+		nonce = p.nonce
+	}
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   p.URL,
+		"sub":   "subject-1",
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.sign(claims),
+	})
+}
+
+// sign returns claims as a JWT signed with RS256.
+func (p *mockProvider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+// authorize plays the user approving the login that redirected to location
+// and returns the code and state the provider sends back.
+func (p *mockProvider) authorize(t *testing.T, location string) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code = "code-" + q.Get("state")
+	p.codes[code] = authorization{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	return code, q.Get("state")
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		allowedDomains []string
+		claims         map[string]any
+		nonce          string
+		// Changes the callback query or the login cookie
+		tamper func(q url.Values, cookie *http.Cookie)
+		want   int
+	}{
+		{
+			name: "success",
+			want: http.StatusFound,
+		},
+		{
+			name: "state mismatch",
+			tamper: func(q url.Values, cookie *http.Cookie) {
+				q.Set("state", "forged")
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "missing login cookie",
+			tamper: func(q url.Values, cookie *http.Cookie) {
+				cookie.Value = ""
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:  "nonce mismatch",
+			nonce: "replayed",
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name: "PKCE verifier mismatch",
+			tamper: func(q url.Values, cookie *http.Cookie) {
+				// gin escapes cookie values
+				value, _ := url.QueryUnescape(cookie.Value)
+				parts := strings.Split(value, ":")
+				parts[2] = "wrong-verifier-wrong-verifier-wrong-verifier"
+				cookie.Value = url.QueryEscape(strings.Join(parts, ":"))
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:           "verified email in allowed domain",
+			allowedDomains: []string{"example.com"},
+			claims:         map[string]any{"email": "ada@Example.com", "email_verified": true},
+			want:           http.StatusFound,
+		},
+		{
+			name:           "email outside allowed domains",
+			allowedDomains: []string{"example.com"},
+			claims:         map[string]any{"email": "ada@example.org", "email_verified": true},
+			want:           http.StatusForbidden,
+		},
+		{
+			name:           "unverified email",
+			allowedDomains: []string{"example.com"},
+			claims:         map[string]any{"email": "ada@example.com", "email_verified": false},
+			want:           http.StatusForbidden,
+		},
+		{
+			name:           "email without email_verified",
+			allowedDomains: []string{"example.com"},
+			claims:         map[string]any{"email": "ada@example.com"},
+			want:           http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newMockProvider(t)
+			provider.claims = tt.claims
+			provider.nonce = tt.nonce
+
+			users := repository.NewMemoryPasteStore()
+			h, err := NewOIDCHandler(context.Background(), users, &config.OIDCConfig{
+				Issuer:         provider.URL,
+				ClientID:       testClientID,
+				ClientSecret:   "secret",
+				RedirectURL:    "http://runbin.test/api/auth/callback",
+				AllowedDomains: tt.allowedDomains,
+				SessionTTL:     3600,
+				LoginRedirect:  "/",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine := gin.New()
+			engine.GET("/api/auth/login", h.Login)
+			engine.GET("/api/auth/callback", h.Callback)
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/login", nil))
+			if rec.Code != http.StatusFound {
+				t.Fatalf("login status = %d, want %d", rec.Code, http.StatusFound)
+			}
+			loginCookie := findCookie(rec.Result().Cookies(), loginCookie)
+			if loginCookie == nil {
+				t.Fatal("login did not set the login cookie")
+			}
+
+			code, state := provider.authorize(t, rec.Header().Get("Location"))
+			q := url.Values{"code": {code}, "state": {state}}
+			if tt.tamper != nil {
+				tt.tamper(q, loginCookie)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/callback?"+q.Encode(), nil)
+			req.AddCookie(&http.Cookie{Name: loginCookie.Name, Value: loginCookie.Value})
+			rec = httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("callback status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			session := findCookie(rec.Result().Cookies(), middleware.SessionCookie)
+			if tt.want == http.StatusFound {
+				if session == nil || session.Value == "" {
+					t.Fatal("successful login did not set a session cookie")
+				}
+				if _, ok := users.GetUserByIdentity(provider.URL, "subject-1"); !ok {
+					t.Error("successful login did not create a user")
+				}
+			} else if session != nil && session.Value != "" {
+				t.Error("failed login set a session cookie")
+			}
+		})
+	}
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, c := range cookies {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
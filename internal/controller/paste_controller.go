@@ -1,80 +1,827 @@
 package controller
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"runbin/internal/apierror"
+	"runbin/internal/auth"
+	"runbin/internal/config"
+	"runbin/internal/dto"
+	"runbin/internal/middleware"
 	"runbin/internal/model"
+	"runbin/internal/notify"
 	"runbin/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type PasteHandler struct {
-	repo     repository.PasteRepository
+	repo repository.PasteRepository
+	hub  *notify.Hub
+	cfg  *config.ApiConfig
 }
 
-func NewPasteHandler(repo repository.PasteRepository) *PasteHandler {
+func NewPasteHandler(repo repository.PasteRepository, hub *notify.Hub, cfg *config.ApiConfig) *PasteHandler {
 	return &PasteHandler{
-		repo:    repo,
+		repo: repo,
+		hub:  hub,
+		cfg:  cfg,
 	}
 }
 
 func (h *PasteHandler) SubmitPaste(c *gin.Context) {
 	var req model.SubmitRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+	if !bindJSON(c, &req) || !h.validateSource(c, req.Code, req.Language, req.Stdin) ||
+		!h.validateCallback(c, req.CallbackURL, req.Run) {
 		return
 	}
 
-	paste := &model.Paste{
-		ID:        uuid.NewString(),
-		Code:      req.Code,
-		Language:  req.Language,
-		Stdin:     req.Stdin,
-		Status:    model.StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	expiresAt, err := h.expiry(req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
 	}
 
-	if !req.Run {
-		paste.Status = model.StatusCompleted
+	paste := newPaste(req.Code, req.Language, req.Stdin)
+	paste.ExpiresAt = expiresAt
+	paste.BurnAfterReading = req.BurnAfterReading
+	paste.CallbackURL = req.CallbackURL
+	if err := setVisibility(paste, req.Visibility, req.Password); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
 	}
+	h.createPaste(c, paste, req.Run)
+}
 
-	if err := h.repo.Save(paste); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Internal Server Error",
-		})
-		log.Printf("Paste save error: %v", err)
+// Page size of listings when the request does not set one.
+const defaultListLimit = 20
+
+// ListPastes lists public pastes, newest first, with optional filters and
+// full-text search over the code. Pages are linked by an opaque cursor.
+// The owner filter accepts "me" for the authenticated user.
+func (h *PasteHandler) ListPastes(c *gin.Context) {
+	var req model.ListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid query: "+err.Error())
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":  "Created",
-		"paste_id": paste.ID,
-		"url":      fmt.Sprintf("/api/pastes/%s", paste.ID),
-	})
+	filter := model.PasteFilter{
+		Language:      req.Language,
+		Status:        req.Status,
+		OwnerID:       req.Owner,
+		BackEnd:       req.BackEnd,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Query:         req.Query,
+		Limit:         req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+	key, authenticated := middleware.CurrentKey(c)
+	if authenticated {
+		filter.ViewerID = key.UserID
+	}
+	if req.Owner == "me" {
+		if !authenticated {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required")
+			return
+		}
+		filter.OwnerID = key.UserID
+	}
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid cursor")
+			return
+		}
+		filter.After = cursor
+	}
 
-	if req.Run {
-		go h.repo.DispatchExecutionTask(paste.ID)
+	// Ask for one more than a page to learn whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	pastes, err := h.repo.ListPastes(filter)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste list error: %v", err)
+		return
+	}
+
+	resp := gin.H{}
+	var meta *dto.Meta
+	if len(pastes) > limit {
+		pastes = pastes[:limit]
+		last := pastes[limit-1]
+		cursor := encodeCursor(&model.PasteCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		resp["next_cursor"] = cursor
+		meta = &dto.Meta{NextCursor: cursor}
+	}
+	if pastes == nil {
+		pastes = []*model.Paste{}
 	}
+	resp["pastes"] = pastes
+	respondPage(c, http.StatusOK, resp, dto.NewPastes(pastes), meta)
+}
+
+// encodeCursor and decodeCursor convert a listing position to and from the
+// opaque form handed to clients.
+func encodeCursor(cursor *model.PasteCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*model.PasteCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PasteCursor{CreatedAt: t, ID: id}, nil
+}
+
+// pasteResponse is a paste together with its most recent run.
+type pasteResponse struct {
+	*model.Paste
+	LatestRun *model.Execution `json:"latest_run,omitempty"`
+	RunsURL   string           `json:"runs_url"`
 }
 
 func (h *PasteHandler) GetPaste(c *gin.Context) {
-	pasteID := c.Param("id")
-	paste, exists := h.repo.GetByID(pasteID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Paste not found"})
+	paste, ok := h.loadPaste(c)
+	if !ok {
+		return
+	}
+	if paste, ok = h.awaitResult(c, paste); !ok {
+		return
+	}
+
+	// A burn-after-reading paste is shown once its result is final, to the
+	// one reader that manages to burn it
+	if paste.BurnAfterReading && paste.Status.IsTerminal() {
+		burned, err := h.repo.Burn(paste.ID)
+		if err != nil {
+			apierror.Internal(c)
+			log.Printf("Paste burn error: %v", err)
+			return
+		}
+		if !burned {
+			apierror.Respond(c, http.StatusGone, apierror.CodeExpired, "Paste has expired")
+			return
+		}
+	}
+
+	runs, err := h.repo.GetExecutions(paste.ID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste runs error: %v", err)
+		return
+	}
+
+	resp := pasteResponse{
+		Paste:   paste,
+		RunsURL: fmt.Sprintf("/api/pastes/%s/runs", paste.ID),
+	}
+	data := dto.NewPaste(paste)
+	if len(runs) > 0 {
+		resp.LatestRun = runs[0]
+		data.LatestRun = dto.NewRun(runs[0])
+	}
+	respond(c, http.StatusOK, resp, data)
+}
+
+// awaitResult implements the wait parameter of GetPaste: it blocks until
+// the paste's status is terminal, the wait elapses or the client goes away,
+// and returns the paste as it is then.
+func (h *PasteHandler) awaitResult(c *gin.Context, paste *model.Paste) (*model.Paste, bool) {
+	param := c.Query("wait")
+	if param == "" {
+		return paste, true
+	}
+	wait, err := time.ParseDuration(param)
+	if err != nil || wait < 0 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, "wait must be a duration such as 30s")
+		return nil, false
+	}
+	wait = min(wait, time.Duration(h.cfg.App.MaxWait)*time.Second)
+	if paste.Status.IsTerminal() || wait == 0 {
+		return paste, true
+	}
+
+	changed, unsubscribe := h.hub.Subscribe(paste.ID)
+	defer unsubscribe()
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	// Read the paste again once subscribed, so that a change made in
+	// between is not missed
+	for {
+		current, exists := h.repo.GetByID(paste.ID)
+		if !exists {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Paste not found")
+			return nil, false
+		}
+		paste = current
+		if paste.Status.IsTerminal() {
+			return paste, true
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return paste, true
+		case <-c.Request.Context().Done():
+			return paste, true
+		}
+	}
+}
+
+// RunPaste queues another run of a paste's stored code with new stdin or
+// limits. The paste's own result is left untouched.
+func (h *PasteHandler) RunPaste(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+
+	var req model.RunRequest
+	if !bindOptionalJSON(c, &req) || !h.validateSource(c, paste.Code, paste.Language, req.Stdin) {
+		return
+	}
+
+	if !h.withinQuota(c, 1) {
+		return
+	}
+
+	run := &model.Execution{
+		ID:          uuid.NewString(),
+		PasteID:     paste.ID,
+		Stdin:       req.Stdin,
+		TimeLimit:   req.TimeLimit,
+		MemoryLimit: req.MemoryLimit,
+		Status:      model.StatusPending,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if key, ok := middleware.CurrentKey(c); ok {
+		run.RequestedBy = key.UserID
+	}
+	if err := h.repo.SaveExecution(run); err != nil {
+		apierror.Internal(c)
+		log.Printf("Execution save error: %v", err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, gin.H{
+		"message": "Created",
+		"run_id":  run.ID,
+		"url":     fmt.Sprintf("/api/pastes/%s/runs/%s", paste.ID, run.ID),
+	}, &dto.RunCreated{
+		ID:  run.ID,
+		URL: dto.RunURL(paste.ID, run.ID),
+	})
+
+	go h.repo.DispatchExecutionTask(run.ID)
+}
+
+// GetRuns lists all runs of a paste, newest first.
+func (h *PasteHandler) GetRuns(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+
+	runs, err := h.repo.GetExecutions(paste.ID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste runs error: %v", err)
+		return
+	}
+	if runs == nil {
+		runs = []*model.Execution{}
+	}
+	respond(c, http.StatusOK, gin.H{
+		"runs": runs,
+	}, dto.NewRuns(runs))
+}
+
+func (h *PasteHandler) GetRun(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+
+	run, exists := h.repo.GetExecution(c.Param("run_id"))
+	if !exists || run.PasteID != c.Param("id") {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Run not found")
 		return
 	}
-	c.JSON(http.StatusOK, paste)
+	respond(c, http.StatusOK, run, dto.NewRun(run))
+}
+
+// DeletePaste removes a paste. It requires the management token returned
+// when the paste was created or an API key of its owner.
+func (h *PasteHandler) DeletePaste(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || !authorizeOwner(c, paste) {
+		return
+	}
+
+	if err := h.repo.Delete(paste.ID); err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste delete error: %v", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PatchPaste changes the settings of a paste. It requires the management
+// token returned when the paste was created or an API key of its owner.
+func (h *PasteHandler) PatchPaste(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || !authorizeOwner(c, paste) {
+		return
+	}
+
+	var req model.PatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// The store may share the paste with other requests, so the changes go
+	// to a copy that is only saved once all of them are valid
+	updated := *paste
+	if req.ExpiresIn != "" || req.ExpiresAt != nil {
+		expiresAt, err := h.expiry(req.ExpiresIn, req.ExpiresAt)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+			return
+		}
+		// The maximum retention counts from the creation of the paste, so
+		// it cannot be extended by patching the expiry again
+		updated.ExpiresAt = h.capRetention(expiresAt, paste.CreatedAt)
+	}
+
+	if req.Visibility != nil || req.Password != nil {
+		var visibility model.Visibility
+		if req.Visibility != nil {
+			visibility = *req.Visibility
+		}
+		var password string
+		if req.Password != nil {
+			password = *req.Password
+		}
+		if err := setVisibility(&updated, visibility, password); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	if err := h.repo.UpdateSettings(&updated); err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste update error: %v", err)
+		return
+	}
+	respond(c, http.StatusOK, gin.H{
+		"message":    "Updated",
+		"paste_id":   updated.ID,
+		"expires_at": updated.ExpiresAt,
+		"visibility": updated.Visibility,
+	}, dto.NewPaste(&updated))
+}
+
+// ForkPaste creates a new revision of a paste. Fields left out of the
+// request are taken from the parent.
+func (h *PasteHandler) ForkPaste(c *gin.Context) {
+	parent, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, parent) {
+		return
+	}
+
+	// An empty body forks the paste unchanged
+	var req model.ForkRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	code, language, stdin := parent.Code, parent.Language, parent.Stdin
+	if req.Code != nil {
+		code = *req.Code
+	}
+	if req.Language != nil {
+		language = *req.Language
+	}
+	if req.Stdin != nil {
+		stdin = *req.Stdin
+	}
+	if !h.validateSource(c, code, language, stdin) {
+		return
+	}
+
+	expiresAt, err := h.expiry("", nil)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Forks keep the visibility of their parent, including its password
+	paste := newPaste(code, language, stdin)
+	paste.ExpiresAt = expiresAt
+	paste.Visibility = parent.Visibility
+	paste.PasswordHash = parent.PasswordHash
+	paste.ParentID = parent.ID
+	paste.Revision = parent.Revision + 1
+	h.createPaste(c, paste, req.Run)
+}
+
+// GetHistory lists the lineage of a paste, from the original down to the
+// requested revision, each with its own execution result.
+func (h *PasteHandler) GetHistory(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+
+	history, err := h.repo.GetHistory(paste.ID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste history error: %v", err)
+		return
+	}
+
+	// Expired ancestors stay in the chain until the reaper deletes them, and
+	// restricted ancestors are only shown through their own ID
+	now := time.Now()
+	visible := make([]*model.Paste, 0, len(history))
+	for _, p := range history {
+		if p.IsExpired(now) {
+			continue
+		}
+		if p.ID != paste.ID && p.Visibility != model.VisibilityPublic && p.Visibility != model.VisibilityUnlisted {
+			continue
+		}
+		visible = append(visible, p)
+	}
+	respond(c, http.StatusOK, gin.H{
+		"history": visible,
+	}, dto.NewPastes(visible))
 }
 
 func (h *PasteHandler) GetLanguages(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"languages": []string{"c++20"},
+	names := make([]string, 0, len(model.Languages))
+	for _, lang := range model.Languages {
+		names = append(names, lang.Name)
+	}
+	respond(c, http.StatusOK, gin.H{
+		"languages": names,
+	}, dto.NewLanguages(model.Languages))
+}
+
+// copyResult links p to the execution result of prev instead of running it
+// again.
+func copyResult(p, prev *model.Paste) {
+	p.Status = prev.Status
+	p.Stdout = prev.Stdout
+	p.Stderr = prev.Stderr
+	p.StdoutTruncated = prev.StdoutTruncated
+	p.StderrTruncated = prev.StderrTruncated
+	p.CompileLog = prev.CompileLog
+	p.ExecutionTimeMs = prev.ExecutionTimeMs
+	p.MemoryUsageKb = prev.MemoryUsageKb
+	p.ExitCode = prev.ExitCode
+	p.BackEnd = prev.BackEnd
+	p.Toolchain = prev.Toolchain
+	p.CachedFrom = prev.ID
+}
+
+func newPaste(code, language, stdin string) *model.Paste {
+	return &model.Paste{
+		ID:            uuid.NewString(),
+		Code:          code,
+		Language:      language,
+		Stdin:         stdin,
+		Status:        model.StatusPending,
+		Visibility:    model.VisibilityPublic,
+		Revision:      1,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		ExecutionHash: model.ExecutionHash(code, language, stdin),
+	}
+}
+
+// createPaste stores a new paste and, if requested, queues its execution or
+// links it to a reusable earlier result.
+func (h *PasteHandler) createPaste(c *gin.Context, paste *model.Paste, run bool) {
+	token, ok := h.savePaste(c, paste, run)
+	if !ok {
+		return
+	}
+
+	resp := gin.H{
+		"message":          "Created",
+		"paste_id":         paste.ID,
+		"url":              fmt.Sprintf("/api/pastes/%s", paste.ID),
+		"management_token": token,
+	}
+	if paste.CachedFrom != "" {
+		resp["cached_from"] = paste.CachedFrom
+	}
+	respond(c, http.StatusAccepted, resp, &dto.Created{
+		ID:              paste.ID,
+		URL:             dto.PasteURL(paste.ID),
+		ManagementToken: token,
+		CachedFrom:      paste.CachedFrom,
 	})
+
+	h.dispatch(paste, run)
+}
+
+// savePaste stores a new paste with its initial execution, if requested, and
+// returns its management token. It writes an error response on failure.
+// The execution is not queued until dispatch is called, so the response can
+// be sent first.
+func (h *PasteHandler) savePaste(c *gin.Context, paste *model.Paste, run bool) (string, bool) {
+	if run && !h.withinQuota(c, 1) {
+		return "", false
+	}
+
+	token, ok := h.preparePaste(c, paste, run)
+	if !ok {
+		return "", false
+	}
+
+	if err := h.repo.Save(paste); err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste save error: %v", err)
+		return "", false
+	}
+
+	if run {
+		if err := h.repo.SaveExecution(initialExecution(paste)); err != nil {
+			apierror.Internal(c)
+			log.Printf("Execution save error: %v", err)
+			return "", false
+		}
+	}
+	return token, true
+}
+
+// preparePaste fills in what a new paste gets on submission: a result
+// reused by deduplication, its management token, which it returns, and its
+// owner. It writes an error response on failure.
+func (h *PasteHandler) preparePaste(c *gin.Context, paste *model.Paste, run bool) (string, bool) {
+	if !run {
+		paste.Status = model.StatusCompleted
+	} else if h.cfg.Dedup.Enabled {
+		since := time.Now().Add(-time.Duration(h.cfg.Dedup.Window) * time.Second)
+		if prev, ok := h.repo.FindReusableResult(paste.ExecutionHash, since); ok {
+			copyResult(paste, prev)
+		}
+	}
+
+	token, tokenHash, err := auth.NewToken(managementTokenPrefix)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste token error: %v", err)
+		return "", false
+	}
+	paste.TokenHash = tokenHash
+	if key, ok := middleware.CurrentKey(c); ok {
+		paste.OwnerID = key.UserID
+	}
+	return token, true
+}
+
+// dispatch queues the initial execution of a paste saved by savePaste,
+// unless deduplication already linked a result.
+func (h *PasteHandler) dispatch(paste *model.Paste, run bool) {
+	if run && paste.CachedFrom == "" {
+		go h.repo.DispatchExecutionTask(paste.ID)
+	}
+}
+
+// initialExecution returns the run requested on submission. It shares the
+// paste's ID and carries over a result already linked by deduplication.
+func initialExecution(p *model.Paste) *model.Execution {
+	return &model.Execution{
+		ID:              p.ID,
+		PasteID:         p.ID,
+		Stdin:           p.Stdin,
+		Status:          p.Status,
+		Stdout:          p.Stdout,
+		Stderr:          p.Stderr,
+		StdoutTruncated: p.StdoutTruncated,
+		StderrTruncated: p.StderrTruncated,
+		CompileLog:      p.CompileLog,
+		ExecutionTimeMs: p.ExecutionTimeMs,
+		MemoryUsageKb:   p.MemoryUsageKb,
+		ExitCode:        p.ExitCode,
+		CacheHit:        p.CacheHit,
+		Toolchain:       p.Toolchain,
+		BackEnd:         p.BackEnd,
+		RequestedBy:     p.OwnerID,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
+// withinQuota checks the daily CPU quota of the authenticated user before
+// runs are queued and writes a 429 response if too little of it is left.
+// Each run needs a second of the quota, with the remainder rounded up, so a
+// single run is queued while any quota is left. Anonymous requests are only
+// rate limited, and admins have no quota.
+func (h *PasteHandler) withinQuota(c *gin.Context, runs int) bool {
+	key, ok := middleware.CurrentKey(c)
+	if !ok || h.cfg.Quota.CPUSeconds <= 0 || key.HasScope(model.ScopeAdmin) {
+		return true
+	}
+
+	now := time.Now().UTC()
+	used, err := h.repo.GetCPUUsage(key.UserID, now)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("CPU usage error: %v", err)
+		return false
+	}
+	remaining := int64(h.cfg.Quota.CPUSeconds)*1000 - used
+	if remaining > int64(runs-1)*1000 {
+		return true
+	}
+
+	message := "Daily CPU quota exceeded"
+	if remaining > 0 {
+		message = fmt.Sprintf("Daily CPU quota left is too small for %d runs", runs)
+	}
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	middleware.AbortTooManyRequests(c, tomorrow.Sub(now), apierror.CodeQuotaExceeded, message)
+	return false
+}
+
+// loadPaste looks up the paste named by the id parameter. It writes a 404
+// response if there is no such paste and a 410 response if it has expired
+// but has not been deleted yet.
+func (h *PasteHandler) loadPaste(c *gin.Context) (*model.Paste, bool) {
+	paste, exists := h.repo.GetByID(c.Param("id"))
+	if !exists {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Paste not found")
+		return nil, false
+	}
+	if paste.IsExpired(time.Now()) {
+		apierror.Respond(c, http.StatusGone, apierror.CodeExpired, "Paste has expired")
+		return nil, false
+	}
+
+	switch paste.Visibility {
+	case model.VisibilityPrivate:
+		// Do not reveal that a private paste exists
+		if !isOwner(c, paste) {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Paste not found")
+			return nil, false
+		}
+	case model.VisibilityPassword:
+		if isOwner(c, paste) {
+			break
+		}
+		password := c.GetHeader("X-Paste-Password")
+		if password == "" {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodePasswordRequired, "Password required")
+			return nil, false
+		}
+		if bcrypt.CompareHashAndPassword([]byte(paste.PasswordHash), []byte(password)) != nil {
+			apierror.Respond(c, http.StatusForbidden, apierror.CodeInvalidPassword, "Invalid password")
+			return nil, false
+		}
+	}
+	return paste, true
+}
+
+// rejectBurnAfterReading writes a 403 response for a burn-after-reading
+// paste, which may only be read once through GetPaste.
+func rejectBurnAfterReading(c *gin.Context, paste *model.Paste) bool {
+	if !paste.BurnAfterReading {
+		return false
+	}
+	apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Burn-after-reading paste can only be read once")
+	return true
+}
+
+// expiry resolves the expiry requested for a new paste. The earlier of
+// expiresIn and expiresAt wins, and the server's maximum retention caps
+// both. A nil result means the paste never expires.
+func (h *PasteHandler) expiry(expiresIn string, expiresAt *time.Time) (*time.Time, error) {
+	now := time.Now()
+
+	var t *time.Time
+	if expiresIn != "" {
+		d, err := time.ParseDuration(expiresIn)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("Invalid expires_in: %q", expiresIn)
+		}
+		at := now.Add(d)
+		t = &at
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, errors.New("Invalid expires_at: must be in the future")
+		}
+		if t == nil || expiresAt.Before(*t) {
+			t = expiresAt
+		}
+	}
+
+	return h.capRetention(t, now), nil
+}
+
+// capRetention caps expiresAt at the server's maximum retention of a paste
+// created at createdAt. A nil expiresAt, meaning never, is capped as well.
+func (h *PasteHandler) capRetention(expiresAt *time.Time, createdAt time.Time) *time.Time {
+	if h.cfg.Retention.MaxAge <= 0 {
+		return expiresAt
+	}
+	limit := createdAt.Add(time.Duration(h.cfg.Retention.MaxAge) * time.Second)
+	if expiresAt == nil || expiresAt.After(limit) {
+		return &limit
+	}
+	return expiresAt
+}
+
+// Prefix of management tokens, so they are recognisable when leaked.
+const managementTokenPrefix = "rbm_"
+
+// authorizeOwner checks that the request may manage paste, either with the
+// management token sent in the X-Management-Token header or with an API key
+// of the paste's owner. It writes a 401 or 403 response if not.
+func authorizeOwner(c *gin.Context, paste *model.Paste) bool {
+	_, authenticated := middleware.CurrentKey(c)
+	if c.GetHeader("X-Management-Token") == "" && !authenticated {
+		apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Management token required")
+		return false
+	}
+	if !isOwner(c, paste) {
+		apierror.Respond(c, http.StatusForbidden, apierror.CodeForbidden, "Invalid management token")
+		return false
+	}
+	return true
+}
+
+// isOwner reports whether the request carries the management token of
+// paste or an API key of its owner. Admin keys own every paste.
+func isOwner(c *gin.Context, paste *model.Paste) bool {
+	if key, ok := middleware.CurrentKey(c); ok {
+		if key.HasScope(model.ScopeAdmin) || (paste.OwnerID != "" && key.UserID == paste.OwnerID) {
+			return true
+		}
+	}
+	return auth.TokenMatches(c.GetHeader("X-Management-Token"), paste.TokenHash)
+}
+
+// setVisibility validates and applies a requested visibility. A password
+// without a visibility makes the paste password-protected, and switching to
+// password protection without a new password keeps the current one.
+func setVisibility(paste *model.Paste, visibility model.Visibility, password string) error {
+	if visibility == "" {
+		if password == "" {
+			return nil
+		}
+		visibility = model.VisibilityPassword
+	}
+	if !visibility.IsValid() {
+		return fmt.Errorf("Invalid visibility: %q", visibility)
+	}
+
+	if visibility != model.VisibilityPassword {
+		paste.Visibility = visibility
+		paste.PasswordHash = ""
+		return nil
+	}
+
+	if password == "" {
+		if paste.Visibility == model.VisibilityPassword && paste.PasswordHash != "" {
+			return nil
+		}
+		return errors.New("Password required for password-protected pastes")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("Invalid password: %v", err)
+	}
+	paste.Visibility = visibility
+	paste.PasswordHash = string(hash)
+	return nil
 }
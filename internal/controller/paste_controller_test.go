@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"runbin/internal/auth"
+	"runbin/internal/config"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+	"runbin/internal/notify"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPatchPasteExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const maxAge = 24 * time.Hour
+	createdAt := time.Now().Add(-20 * time.Hour).Truncate(time.Second)
+	retentionEnd := createdAt.Add(maxAge)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+		// Expiry of the stored paste after the request
+		wantExpiry func(now time.Time) time.Time
+	}{
+		{
+			name:       "earlier expiry",
+			body:       `{"expires_in":"1h"}`,
+			want:       http.StatusOK,
+			wantExpiry: func(now time.Time) time.Time { return now.Add(time.Hour) },
+		},
+		{
+			name:       "capped at creation plus maximum retention",
+			body:       `{"expires_in":"48h"}`,
+			want:       http.StatusOK,
+			wantExpiry: func(time.Time) time.Time { return retentionEnd },
+		},
+		{
+			name:       "invalid visibility changes nothing",
+			body:       `{"expires_in":"1h","visibility":"secret"}`,
+			want:       http.StatusBadRequest,
+			wantExpiry: func(time.Time) time.Time { return retentionEnd },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemoryPasteStore()
+			if err := store.SaveAPIKey(&model.APIKey{
+				ID:      "key-1",
+				UserID:  "user-1",
+				KeyHash: auth.HashToken(testAPIKey),
+				Scopes:  []model.Scope{model.ScopeSubmit},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			paste := newPaste("int main() {}", "c++20", "")
+			paste.OwnerID = "user-1"
+			paste.CreatedAt = createdAt
+			paste.ExpiresAt = &retentionEnd
+			if err := store.Save(paste); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &config.ApiConfig{Retention: config.RetentionConfig{MaxAge: int(maxAge.Seconds())}}
+			h := NewPasteHandler(store, notify.NewHub(), cfg)
+			engine := gin.New()
+			engine.PATCH("/pastes/:id", middleware.Authenticate(store), h.PatchPaste)
+
+			r := httptest.NewRequest(http.MethodPatch, "/pastes/"+paste.ID, bytes.NewBufferString(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer "+testAPIKey)
+			rec := httptest.NewRecorder()
+			now := time.Now()
+			engine.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+
+			stored, _ := store.GetByID(paste.ID)
+			want := tt.wantExpiry(now)
+			if stored.ExpiresAt == nil || stored.ExpiresAt.Sub(want).Abs() > time.Second {
+				t.Errorf("stored expiry = %v, want %v", stored.ExpiresAt, want)
+			}
+			if !paste.ExpiresAt.Equal(retentionEnd) || paste.Visibility != model.VisibilityPublic {
+				t.Errorf("the paste read before the request was changed in place")
+			}
+		})
+	}
+}
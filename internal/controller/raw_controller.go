@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"archive/zip"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRawCode returns the source of a paste as plain text.
+func (h *PasteHandler) GetRawCode(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+	writeText(c, sourceFileName(paste), paste.Code)
+}
+
+// GetRawOutput returns one output of a paste's own run, named by the
+// stream parameter, as plain text.
+func (h *PasteHandler) GetRawOutput(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+
+	switch c.Param("stream") {
+	case "stdout":
+		writeText(c, "stdout.txt", paste.Stdout)
+	case "stderr":
+		writeText(c, "stderr.txt", paste.Stderr)
+	case "compile_log":
+		writeText(c, "compile_log.txt", paste.CompileLog)
+	default:
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Not found")
+	}
+}
+
+// GetArchive returns a zip archive of a paste's source, stdin and outputs.
+// Runs other than the initial one are stored under runs/<run id>/.
+func (h *PasteHandler) GetArchive(c *gin.Context) {
+	paste, ok := h.loadPaste(c)
+	if !ok || rejectBurnAfterReading(c, paste) {
+		return
+	}
+
+	runs, err := h.repo.GetExecutions(paste.ID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("Paste runs error: %v", err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, paste.ID))
+	c.Status(http.StatusOK)
+
+	files := []archiveFile{
+		{sourceFileName(paste), paste.Code},
+		{"stdin.txt", paste.Stdin},
+		{"stdout.txt", paste.Stdout},
+		{"stderr.txt", paste.Stderr},
+		{"compile_log.txt", paste.CompileLog},
+	}
+	for _, run := range runs {
+		if run.IsInitial() {
+			continue
+		}
+		dir := path.Join("runs", run.ID)
+		files = append(files,
+			archiveFile{path.Join(dir, "stdin.txt"), run.Stdin},
+			archiveFile{path.Join(dir, "stdout.txt"), run.Stdout},
+			archiveFile{path.Join(dir, "stderr.txt"), run.Stderr},
+			archiveFile{path.Join(dir, "compile_log.txt"), run.CompileLog},
+		)
+	}
+
+	// The status line is already sent, so a failure can only be logged
+	zw := zip.NewWriter(c.Writer)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: paste.UpdatedAt.In(time.UTC),
+		})
+		if err == nil {
+			_, err = w.Write([]byte(f.content))
+		}
+		if err != nil {
+			log.Printf("Paste archive error: %v", err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Paste archive error: %v", err)
+	}
+}
+
+type archiveFile struct {
+	name    string
+	content string
+}
+
+// sourceFileName names the source file of a paste after its language.
+func sourceFileName(paste *model.Paste) string {
+	lang, ok := model.LookupLanguage(paste.Language)
+	if !ok {
+		return "main.txt"
+	}
+	return "main" + lang.Extensions[0]
+}
+
+// writeText writes content as a plain-text response that browsers display
+// inline and save under name.
+func writeText(c *gin.Context, name, content string) {
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, name))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(content))
+}
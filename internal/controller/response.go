@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"runbin/internal/dto"
+	"runbin/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respond writes a successful response. The versioned API wraps data in the
+// envelope shared by all its responses, while the legacy routes keep
+// writing legacy, the body they have always returned.
+func respond(c *gin.Context, status int, legacy any, data any) {
+	respondPage(c, status, legacy, data, nil)
+}
+
+// respondPage is respond for responses that carry metadata, such as the
+// cursor of a listing.
+func respondPage(c *gin.Context, status int, legacy any, data any, meta *dto.Meta) {
+	if middleware.Version(c) == 0 {
+		c.JSON(status, legacy)
+		return
+	}
+	c.JSON(status, dto.Envelope{Data: data, Meta: meta})
+}
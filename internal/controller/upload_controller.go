@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"runbin/internal/apierror"
+	"runbin/internal/dto"
+	"runbin/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// upload is a paste submitted from a terminal rather than as JSON.
+type upload struct {
+	code     string
+	stdin    string
+	filename string
+	language string
+	run      bool
+	expires  string
+	visible  model.Visibility
+}
+
+// Upload creates a paste pastebin-style, for use from a terminal:
+//
+//	curl -F 'code=@main.cpp' -F lang=c++20 host/
+//	cat main.cpp | curl --data-binary @- 'host/?lang=c++20&run=1'
+//
+// A multipart body takes the code, stdin, lang, run, expires_in and
+// visibility fields, and any other body is the code itself with the
+// options in the query string. Without a language, it is inferred from the
+// extension of the uploaded file or of the name query parameter. The
+// response is the paste's URL as plain text; the management token is sent
+// in the X-Management-Token header.
+func (h *PasteHandler) Upload(c *gin.Context) {
+	var up upload
+	var err error
+	if c.ContentType() == "multipart/form-data" {
+		up, err = readMultipartUpload(c)
+	} else {
+		up, err = readRawUpload(c)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Respond(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge,
+				fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if up.language == "" {
+		lang, ok := model.LookupExtension(up.filename)
+		if !ok {
+			apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeUnsupportedLanguage,
+				"Language required: set lang or upload a file with a known extension")
+			return
+		}
+		up.language = lang.Name
+	}
+	if !h.validateSource(c, up.code, up.language, up.stdin) {
+		return
+	}
+
+	expiresAt, err := h.expiry(up.expires, nil)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	paste := newPaste(up.code, up.language, up.stdin)
+	paste.ExpiresAt = expiresAt
+	if err := setVisibility(paste, up.visible, ""); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	token, ok := h.savePaste(c, paste, up.run)
+	if !ok {
+		return
+	}
+	c.Header("X-Management-Token", token)
+	c.String(http.StatusAccepted, "%s\n", h.shareURL(c, paste.ID))
+
+	h.dispatch(paste, up.run)
+}
+
+func readMultipartUpload(c *gin.Context) (upload, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return upload{}, fmt.Errorf("Invalid multipart body: %w", err)
+	}
+
+	up := upload{
+		language: firstValue(form, "lang", "language"),
+		expires:  firstValue(form, "expires_in"),
+		visible:  model.Visibility(firstValue(form, "visibility")),
+	}
+	if up.run, err = parseRun(firstValue(form, "run")); err != nil {
+		return upload{}, err
+	}
+	if up.code, up.filename, err = formText(form, "code"); err != nil {
+		return upload{}, err
+	}
+	if up.stdin, _, err = formText(form, "stdin"); err != nil {
+		return upload{}, err
+	}
+	if up.code == "" {
+		return upload{}, errors.New("Missing code field")
+	}
+	return up, nil
+}
+
+func readRawUpload(c *gin.Context) (upload, error) {
+	// Whatever the content type claims, the body is taken as is. curl
+	// --data-binary labels it as a form, so it must not be parsed as one.
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return upload{}, err
+	}
+
+	up := upload{
+		code:     string(body),
+		stdin:    c.Query("stdin"),
+		filename: c.Query("name"),
+		language: c.Query("lang"),
+		expires:  c.Query("expires_in"),
+		visible:  model.Visibility(c.Query("visibility")),
+	}
+	if up.language == "" {
+		up.language = c.Query("language")
+	}
+	// ?lang=c++20 typed unencoded decodes to "c  20". No language name
+	// contains a space, so put the plus signs back.
+	up.language = strings.ReplaceAll(up.language, " ", "+")
+	if up.run, err = parseRun(c.Query("run")); err != nil {
+		return upload{}, err
+	}
+	if up.code == "" {
+		return upload{}, errors.New("Empty body")
+	}
+	return up, nil
+}
+
+// formText returns a multipart field, which may be sent as a file or as a
+// plain value, together with the file's name.
+func formText(form *multipart.Form, name string) (string, string, error) {
+	if files := form.File[name]; len(files) > 0 {
+		f, err := files[0].Open()
+		if err != nil {
+			return "", "", err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return "", "", err
+		}
+		return string(data), files[0].Filename, nil
+	}
+	return firstValue(form, name), "", nil
+}
+
+func firstValue(form *multipart.Form, names ...string) string {
+	for _, name := range names {
+		if values := form.Value[name]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// parseRun accepts the usual spellings of a boolean; an empty value means
+// false.
+func parseRun(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	run, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("Invalid run: %q", s)
+	}
+	return run, nil
+}
+
+// shareURL returns the address at which a paste can be viewed: the web
+// frontend if its URL is configured, and the versioned API otherwise.
+func (h *PasteHandler) shareURL(c *gin.Context, id string) string {
+	if h.cfg.App.WebURL != "" {
+		return strings.TrimSuffix(h.cfg.App.WebURL, "/") + "/code/" + id
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, dto.PasteURL(id))
+}
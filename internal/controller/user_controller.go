@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/auth"
+	"runbin/internal/dto"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Prefix of API keys, so they are recognisable when leaked.
+const apiKeyPrefix = "rbk_"
+
+// Scopes of the first key of a new user when the request names none.
+var defaultScopes = []model.Scope{model.ScopeRead, model.ScopeSubmit}
+
+type UserHandler struct {
+	users  repository.UserRepository
+	pastes repository.PasteRepository
+}
+
+func NewUserHandler(users repository.UserRepository, pastes repository.PasteRepository) *UserHandler {
+	return &UserHandler{
+		users:  users,
+		pastes: pastes,
+	}
+}
+
+// CreateUser creates a user together with a first API key. Only admins may
+// create users.
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req model.UserRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	if err := validateScopes(scopes); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	user, key, token, err := createUser(h.users, req.Name, scopes)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("User create error: %v", err)
+		return
+	}
+
+	respond(c, http.StatusCreated, gin.H{
+		"user":    user,
+		"key":     key,
+		"api_key": token,
+	}, &dto.CreatedUser{
+		User:   dto.NewUser(user),
+		Key:    dto.NewAPIKey(key),
+		APIKey: token,
+	})
+}
+
+// GetMe returns the authenticated user and the key used for the request.
+func (h *UserHandler) GetMe(c *gin.Context) {
+	key, _ := middleware.CurrentKey(c)
+	user, exists := h.users.GetUser(key.UserID)
+	if !exists {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "User not found")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{
+		"user": user,
+		"key":  key,
+	}, &dto.Me{
+		User: dto.NewUser(user),
+		Key:  dto.NewAPIKey(key),
+	})
+}
+
+// GetMyPastes lists the pastes of the authenticated user, newest first,
+// whatever their visibility.
+func (h *UserHandler) GetMyPastes(c *gin.Context) {
+	key, _ := middleware.CurrentKey(c)
+	pastes, err := h.pastes.GetByOwner(key.UserID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("User pastes error: %v", err)
+		return
+	}
+
+	now := time.Now()
+	live := make([]*model.Paste, 0, len(pastes))
+	for _, p := range pastes {
+		if !p.IsExpired(now) {
+			live = append(live, p)
+		}
+	}
+	respond(c, http.StatusOK, gin.H{
+		"pastes": live,
+	}, dto.NewPastes(live))
+}
+
+// GetKeys lists the API keys of the authenticated user, including revoked
+// ones.
+func (h *UserHandler) GetKeys(c *gin.Context) {
+	key, _ := middleware.CurrentKey(c)
+	keys, err := h.users.GetAPIKeys(key.UserID)
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("API keys error: %v", err)
+		return
+	}
+	if keys == nil {
+		keys = []*model.APIKey{}
+	}
+	respond(c, http.StatusOK, gin.H{
+		"keys": keys,
+	}, dto.NewAPIKeys(keys))
+}
+
+// CreateKey creates another API key for the authenticated user. The new key
+// may not be granted a scope the current key does not have.
+func (h *UserHandler) CreateKey(c *gin.Context) {
+	current, _ := middleware.CurrentKey(c)
+
+	var req model.KeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := validateScopes(req.Scopes); err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !current.HasScope(scope) {
+			apierror.Respond(c, http.StatusForbidden, apierror.CodeInsufficientScope, fmt.Sprintf("Cannot grant the %s scope", scope))
+			return
+		}
+	}
+
+	key, token, err := newAPIKey(current.UserID, req.Name, req.Scopes)
+	if err == nil {
+		err = h.users.SaveAPIKey(key)
+	}
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("API key create error: %v", err)
+		return
+	}
+
+	respond(c, http.StatusCreated, gin.H{
+		"key":     key,
+		"api_key": token,
+	}, &dto.CreatedKey{
+		Key:    dto.NewAPIKey(key),
+		APIKey: token,
+	})
+}
+
+// RevokeKey revokes one of the authenticated user's API keys. Admins may
+// revoke any key.
+func (h *UserHandler) RevokeKey(c *gin.Context) {
+	current, _ := middleware.CurrentKey(c)
+	id := c.Param("id")
+
+	if !current.HasScope(model.ScopeAdmin) {
+		keys, err := h.users.GetAPIKeys(current.UserID)
+		if err != nil {
+			apierror.Internal(c)
+			log.Printf("API keys error: %v", err)
+			return
+		}
+		owned := false
+		for _, k := range keys {
+			if k.ID == id {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "API key not found")
+			return
+		}
+	}
+
+	revoked, err := h.users.RevokeAPIKey(id, time.Now())
+	if err != nil {
+		apierror.Internal(c)
+		log.Printf("API key revoke error: %v", err)
+		return
+	}
+	if !revoked {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "API key not found")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// BootstrapAdmin creates an admin user named name with an admin key if
+// there are no users yet, and returns the key. It returns an empty key if
+// users already exist.
+func BootstrapAdmin(users repository.UserRepository, name string) (string, error) {
+	n, err := users.CountUsers()
+	if err != nil {
+		return "", err
+	}
+	if n > 0 {
+		return "", nil
+	}
+	_, _, token, err := createUser(users, name, []model.Scope{model.ScopeAdmin})
+	return token, err
+}
+
+func createUser(users repository.UserRepository, name string, scopes []model.Scope) (*model.User, *model.APIKey, string, error) {
+	user := &model.User{
+		ID:        uuid.NewString(),
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	key, token, err := newAPIKey(user.ID, "default", scopes)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := users.SaveUser(user); err != nil {
+		return nil, nil, "", fmt.Errorf("save user error: %v", err)
+	}
+	if err := users.SaveAPIKey(key); err != nil {
+		return nil, nil, "", fmt.Errorf("save API key error: %v", err)
+	}
+	return user, key, token, nil
+}
+
+// newAPIKey returns a new key for a user together with the secret, which is
+// only stored hashed.
+func newAPIKey(userID, name string, scopes []model.Scope) (*model.APIKey, string, error) {
+	token, hash, err := auth.NewToken(apiKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	return &model.APIKey{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}, token, nil
+}
+
+func validateScopes(scopes []model.Scope) error {
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return fmt.Errorf("Invalid scope: %q", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"runbin/internal/apierror"
+	"runbin/internal/model"
+	"runbin/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON decodes the request body into req. On failure it writes a 400
+// response, or a 413 response if the body is over the size limit.
+func bindJSON(c *gin.Context, req any) bool {
+	return bind(c, req, false)
+}
+
+// bindOptionalJSON is like bindJSON but also accepts an empty body, which
+// leaves req unchanged.
+func bindOptionalJSON(c *gin.Context, req any) bool {
+	return bind(c, req, true)
+}
+
+func bind(c *gin.Context, req any, optional bool) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil || (optional && errors.Is(err, io.EOF)) {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		apierror.Respond(c, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge,
+			fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+		return false
+	}
+	apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request body: "+err.Error())
+	return false
+}
+
+// validateSource checks code, language and stdin before they are stored and
+// writes a 422 response if they are not acceptable. Sizes are in bytes.
+func (h *PasteHandler) validateSource(c *gin.Context, code, language, stdin string) bool {
+	if err := h.sourceError(code, language, stdin); err != nil {
+		apierror.Respond(c, http.StatusUnprocessableEntity, err.Code, err.Message)
+		return false
+	}
+	return true
+}
+
+func (h *PasteHandler) sourceError(code, language, stdin string) *apierror.Error {
+	if _, ok := model.LookupLanguage(language); !ok {
+		return apierror.New(apierror.CodeUnsupportedLanguage, fmt.Sprintf("Unsupported language: %q", language))
+	}
+	if limit := h.cfg.Submission.MaxCodeSize; limit > 0 && len(code) > limit {
+		return apierror.New(apierror.CodeCodeTooLarge, fmt.Sprintf("Code exceeds %d bytes", limit))
+	}
+	if limit := h.cfg.Submission.MaxStdinSize; limit > 0 && len(stdin) > limit {
+		return apierror.New(apierror.CodeStdinTooLarge, fmt.Sprintf("Stdin exceeds %d bytes", limit))
+	}
+	// NUL bytes cannot be stored in a Postgres text column
+	for field, value := range map[string]string{"code": code, "stdin": stdin} {
+		if !utf8.ValidString(value) {
+			return apierror.New(apierror.CodeInvalidEncoding, fmt.Sprintf("%s is not valid UTF-8", field))
+		}
+		if strings.ContainsRune(value, 0) {
+			return apierror.New(apierror.CodeInvalidEncoding, fmt.Sprintf("%s contains a NUL byte", field))
+		}
+	}
+	return nil
+}
+
+// validateCallback checks the callback URL of a submission, which needs a
+// run to report on. It writes a 400 response if the URL is malformed and a
+// 422 response if its host is not on the allowlist.
+func (h *PasteHandler) validateCallback(c *gin.Context, callbackURL string, run bool) bool {
+	if status, err := h.callbackError(callbackURL, run); err != nil {
+		apierror.Respond(c, status, err.Code, err.Message)
+		return false
+	}
+	return true
+}
+
+// callbackError is validateCallback without the response. It returns the
+// status to reject the URL with.
+func (h *PasteHandler) callbackError(callbackURL string, run bool) (int, *apierror.Error) {
+	if callbackURL == "" {
+		return 0, nil
+	}
+	if !run {
+		return http.StatusBadRequest, apierror.New(apierror.CodeInvalidRequest, "callback_url requires run")
+	}
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return http.StatusBadRequest, apierror.New(apierror.CodeInvalidRequest, "callback_url must be an absolute http or https URL")
+	}
+	if !webhook.HostAllowed(u.Hostname(), h.cfg.Webhook.AllowedHosts) {
+		return http.StatusUnprocessableEntity, apierror.New(apierror.CodeCallbackNotAllowed,
+			fmt.Sprintf("Callbacks to %q are not allowed", u.Hostname()))
+	}
+	return 0, nil
+}
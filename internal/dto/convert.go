@@ -0,0 +1,163 @@
+package dto
+
+import (
+	"runbin/internal/model"
+)
+
+// Base path of the versioned API, for the URLs in responses.
+const basePath = "/api/v1"
+
+func PasteURL(id string) string {
+	return basePath + "/pastes/" + id
+}
+
+func RunURL(pasteID, runID string) string {
+	return PasteURL(pasteID) + "/runs/" + runID
+}
+
+func BatchURL(id string) string {
+	return basePath + "/batches/" + id
+}
+
+func NewPaste(p *model.Paste) *Paste {
+	return &Paste{
+		ID:       p.ID,
+		URL:      PasteURL(p.ID),
+		Code:     p.Code,
+		Language: p.Language,
+		Stdin:    p.Stdin,
+		Status:   p.Status,
+		Result: Result{
+			Stdout:          p.Stdout,
+			Stderr:          p.Stderr,
+			StdoutTruncated: p.StdoutTruncated,
+			StderrTruncated: p.StderrTruncated,
+			CompileLog:      p.CompileLog,
+			ExitCode:        p.ExitCode,
+			ExecutionTimeMs: p.ExecutionTimeMs,
+			MemoryUsageKb:   p.MemoryUsageKb,
+			CacheHit:        p.CacheHit,
+			Toolchain:       p.Toolchain,
+			BackEnd:         p.BackEnd,
+		},
+		Visibility:       p.Visibility,
+		BurnAfterReading: p.BurnAfterReading,
+		OwnerID:          p.OwnerID,
+		ParentID:         p.ParentID,
+		Revision:         p.Revision,
+		CachedFrom:       p.CachedFrom,
+		ExpiresAt:        p.ExpiresAt,
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+func NewPastes(pastes []*model.Paste) []*Paste {
+	out := make([]*Paste, len(pastes))
+	for i, p := range pastes {
+		out[i] = NewPaste(p)
+	}
+	return out
+}
+
+func NewRun(e *model.Execution) *Run {
+	return &Run{
+		ID:          e.ID,
+		PasteID:     e.PasteID,
+		URL:         RunURL(e.PasteID, e.ID),
+		Stdin:       e.Stdin,
+		TimeLimit:   e.TimeLimit,
+		MemoryLimit: e.MemoryLimit,
+		Status:      e.Status,
+		Result: Result{
+			Stdout:          e.Stdout,
+			Stderr:          e.Stderr,
+			StdoutTruncated: e.StdoutTruncated,
+			StderrTruncated: e.StderrTruncated,
+			CompileLog:      e.CompileLog,
+			ExitCode:        e.ExitCode,
+			ExecutionTimeMs: e.ExecutionTimeMs,
+			MemoryUsageKb:   e.MemoryUsageKb,
+			CacheHit:        e.CacheHit,
+			Toolchain:       e.Toolchain,
+			BackEnd:         e.BackEnd,
+		},
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func NewRuns(runs []*model.Execution) []*Run {
+	out := make([]*Run, len(runs))
+	for i, e := range runs {
+		out[i] = NewRun(e)
+	}
+	return out
+}
+
+func NewLanguages(languages []model.Language) []Language {
+	out := make([]Language, len(languages))
+	for i, lang := range languages {
+		out[i] = Language{Name: lang.Name, Extensions: lang.Extensions}
+	}
+	return out
+}
+
+func NewUser(u *model.User) User {
+	return User{
+		ID:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func NewAPIKey(k *model.APIKey) APIKey {
+	return APIKey{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		Name:      k.Name,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+func NewAPIKeys(keys []*model.APIKey) []APIKey {
+	out := make([]APIKey, len(keys))
+	for i, k := range keys {
+		out[i] = NewAPIKey(k)
+	}
+	return out
+}
+
+// NewBatch summarizes the progress of a batch from its pastes, which must be
+// in the order they were submitted.
+func NewBatch(b *model.Batch, pastes []*model.Paste) *Batch {
+	out := &Batch{
+		ID:        b.ID,
+		URL:       BatchURL(b.ID),
+		Items:     make([]*BatchItem, len(pastes)),
+		CreatedAt: b.CreatedAt,
+	}
+	for i, p := range pastes {
+		out.Items[i] = &BatchItem{
+			ID:       p.ID,
+			URL:      PasteURL(p.ID),
+			Language: p.Language,
+			Status:   p.Status,
+			ExitCode: p.ExitCode,
+		}
+		switch p.Status {
+		case model.StatusPending:
+			out.Progress.Pending++
+		case model.StatusRunning:
+			out.Progress.Running++
+		default:
+			out.Progress.Finished++
+		}
+	}
+	out.Progress.Total = len(pastes)
+	out.Progress.Done = out.Progress.Finished == out.Progress.Total
+	return out
+}
@@ -0,0 +1,173 @@
+// Package dto defines the response bodies of the versioned API. They are
+// kept apart from the models so that storage can change without changing
+// what clients see.
+package dto
+
+import (
+	"time"
+
+	"runbin/internal/model"
+)
+
+// Envelope wraps every successful response of the versioned API.
+type Envelope struct {
+	Data any   `json:"data"`
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// Meta carries information about a response rather than its subject.
+type Meta struct {
+	// Cursor of the next page of a listing; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Result is the outcome of running a paste's code.
+type Result struct {
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated"`
+	StderrTruncated bool   `json:"stderr_truncated"`
+	CompileLog      string `json:"compile_log"`
+	ExitCode        *int   `json:"exit_code,omitempty"`
+	ExecutionTimeMs int    `json:"execution_time_ms"`
+	MemoryUsageKb   int    `json:"memory_usage_kb"`
+	CacheHit        bool   `json:"cache_hit"`
+	Toolchain       string `json:"toolchain"`
+	BackEnd         string `json:"backend"`
+}
+
+type Paste struct {
+	ID               string            `json:"id"`
+	URL              string            `json:"url"`
+	Code             string            `json:"code"`
+	Language         string            `json:"language"`
+	Stdin            string            `json:"stdin"`
+	Status           model.PasteStatus `json:"status"`
+	Result           Result            `json:"result"`
+	Visibility       model.Visibility  `json:"visibility"`
+	BurnAfterReading bool              `json:"burn_after_reading"`
+	OwnerID          string            `json:"owner_id,omitempty"`
+	ParentID         string            `json:"parent_id,omitempty"`
+	Revision         int               `json:"revision"`
+	// Paste whose result was reused instead of running the code again
+	CachedFrom string     `json:"cached_from,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	// Most recent run, only included when a single paste is requested
+	LatestRun *Run `json:"latest_run,omitempty"`
+}
+
+// Run is one execution of a paste's code.
+type Run struct {
+	ID          string            `json:"id"`
+	PasteID     string            `json:"paste_id"`
+	URL         string            `json:"url"`
+	Stdin       string            `json:"stdin"`
+	TimeLimit   float32           `json:"time_limit"`
+	MemoryLimit int               `json:"memory_limit"`
+	Status      model.PasteStatus `json:"status"`
+	Result      Result            `json:"result"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Created is returned when a paste is submitted or forked.
+type Created struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Token to manage the paste with; it is only ever returned here
+	ManagementToken string `json:"management_token"`
+	CachedFrom      string `json:"cached_from,omitempty"`
+}
+
+// BatchCreated is returned when a batch is submitted. Its items are in the
+// order they were submitted.
+type BatchCreated struct {
+	ID    string     `json:"id"`
+	URL   string     `json:"url"`
+	Items []*Created `json:"items"`
+}
+
+// Batch is the progress of the pastes submitted together in a batch.
+type Batch struct {
+	ID        string        `json:"id"`
+	URL       string        `json:"url"`
+	Progress  BatchProgress `json:"progress"`
+	Items     []*BatchItem  `json:"items"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// BatchProgress counts the items of a batch by the state of their run.
+type BatchProgress struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	Running  int `json:"running"`
+	Finished int `json:"finished"`
+	// Whether every item is finished
+	Done bool `json:"done"`
+}
+
+type BatchItem struct {
+	ID       string            `json:"id"`
+	URL      string            `json:"url"`
+	Language string            `json:"language"`
+	Status   model.PasteStatus `json:"status"`
+	ExitCode *int              `json:"exit_code,omitempty"`
+}
+
+// RunCreated is returned when another run of a paste is queued.
+type RunCreated struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+type Language struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+}
+
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type APIKey struct {
+	ID        string        `json:"id"`
+	UserID    string        `json:"user_id"`
+	Name      string        `json:"name"`
+	Scopes    []model.Scope `json:"scopes"`
+	CreatedAt time.Time     `json:"created_at"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+}
+
+// CreatedKey is a new API key. The key itself is only ever returned here.
+type CreatedKey struct {
+	Key    APIKey `json:"key"`
+	APIKey string `json:"api_key"`
+}
+
+// CreatedUser is a new user with its first API key.
+type CreatedUser struct {
+	User   User   `json:"user"`
+	Key    APIKey `json:"key"`
+	APIKey string `json:"api_key"`
+}
+
+// Me is the authenticated user and the key used for the request.
+type Me struct {
+	User User   `json:"user"`
+	Key  APIKey `json:"key"`
+}
+
+// Event of the callback sent when the initial run of a paste is finished
+const EventPasteFinished = "paste.finished"
+
+// WebhookEvent is the body of a callback.
+type WebhookEvent struct {
+	Event      string `json:"event"`
+	DeliveryID string `json:"delivery_id"`
+	Paste      *Paste `json:"paste"`
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/auth"
+	"runbin/internal/model"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context key under which Authenticate stores the request's API key.
+const apiKeyContextKey = "runbin.apiKey"
+
+// SessionCookie is the cookie holding the token of a browser session.
+const SessionCookie = "runbin_session"
+
+// Authenticate resolves the API key sent as "Authorization: Bearer <key>"
+// or, failing that, the browser session in SessionCookie. A session is
+// represented as a key with model.SessionScopes. Requests without either
+// continue anonymously; an unknown or revoked key is rejected with 401,
+// while a stale session cookie is ignored.
+func Authenticate(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if key, ok := sessionKey(c, users); ok {
+				c.Set(apiKeyContextKey, key)
+			}
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidAPIKey, "Invalid authorization header")
+			return
+		}
+		key, found := users.GetAPIKeyByHash(auth.HashToken(token))
+		if !found || key.IsRevoked() {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeInvalidAPIKey, "Invalid API key")
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+func sessionKey(c *gin.Context, users repository.UserRepository) (*model.APIKey, bool) {
+	token, err := c.Cookie(SessionCookie)
+	if err != nil || token == "" {
+		return nil, false
+	}
+	session, found := users.GetSession(auth.HashToken(token))
+	if !found || session.IsExpired(time.Now()) {
+		return nil, false
+	}
+	return &model.APIKey{
+		UserID:    session.UserID,
+		Name:      "session",
+		Scopes:    model.SessionScopes,
+		CreatedAt: session.CreatedAt,
+	}, true
+}
+
+// CurrentKey returns the API key the request was authenticated with.
+func CurrentKey(c *gin.Context) (*model.APIKey, bool) {
+	v, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return nil, false
+	}
+	key, ok := v.(*model.APIKey)
+	return key, ok
+}
+
+// HasScope reports whether the request was authenticated with a key that
+// grants scope.
+func HasScope(c *gin.Context, scope model.Scope) bool {
+	key, ok := CurrentKey(c)
+	return ok && key.HasScope(scope)
+}
+
+// RequireAuth rejects anonymous requests with 401.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := CurrentKey(c); !ok {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope rejects anonymous requests with 401 and requests whose key
+// lacks scope with 403.
+func RequireScope(scope model.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := CurrentKey(c)
+		if !ok {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required")
+			return
+		}
+		if !key.HasScope(scope) {
+			apierror.Respond(c, http.StatusForbidden, apierror.CodeInsufficientScope, fmt.Sprintf("API key lacks the %s scope", scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalScope lets anonymous requests through but rejects requests whose
+// key lacks scope with 403, so a key restricted to reading cannot be used
+// to submit.
+func OptionalScope(scope model.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, ok := CurrentKey(c); ok && !key.HasScope(scope) {
+			apierror.Respond(c, http.StatusForbidden, apierror.CodeInsufficientScope, fmt.Sprintf("API key lacks the %s scope", scope))
+			return
+		}
+		c.Next()
+	}
+}
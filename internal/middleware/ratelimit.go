@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/config"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rateLimitContextKey = "runbin.rateLimit"
+
+// limiter is the bucket a request is charged to.
+type limiter struct {
+	store  repository.RateLimitStore
+	key    string
+	bucket config.BucketConfig
+}
+
+func (l *limiter) take(n int) (time.Duration, error) {
+	return l.store.Take(l.key, n, l.bucket.Rate, l.bucket.Burst, time.Now())
+}
+
+// RateLimit takes a token from the bucket of the request's API key or, for
+// anonymous requests, of its client IP, and rejects the request with 429 if
+// the bucket is empty. It must run after Authenticate. Requests are let
+// through if the store fails, so an outage of the limiter does not take the
+// API down with it.
+func RateLimit(store repository.RateLimitStore, cfg *config.RateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l := &limiter{store: store, key: "ip:" + c.ClientIP(), bucket: cfg.IP}
+		if apiKey, ok := CurrentKey(c); ok {
+			// Sessions have no key ID and share a bucket per user
+			l.key = "user:" + apiKey.UserID
+			if apiKey.ID != "" {
+				l.key = "key:" + apiKey.ID
+			}
+			l.bucket = cfg.Key
+		}
+		c.Set(rateLimitContextKey, l)
+
+		wait, err := l.take(1)
+		if err != nil {
+			log.Printf("Rate limit error: %v", err)
+			c.Next()
+			return
+		}
+		if wait > 0 {
+			AbortTooManyRequests(c, wait, apierror.CodeRateLimited, "Rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// TakeTokens charges n tokens on top of the one RateLimit took to the same
+// bucket, for requests that queue several executions. It writes a 429
+// response if the bucket holds fewer than n tokens and a 422 response if it
+// can never hold that many along with the request's own. Requests are let
+// through when rate limiting is disabled or the store fails.
+func TakeTokens(c *gin.Context, n int) bool {
+	v, ok := c.Get(rateLimitContextKey)
+	if !ok || n <= 0 {
+		return true
+	}
+	l := v.(*limiter)
+	if n >= l.bucket.Burst {
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeRateLimited,
+			fmt.Sprintf("Rate limit allows at most %d executions at once", l.bucket.Burst))
+		return false
+	}
+
+	wait, err := l.take(n)
+	if err != nil {
+		log.Printf("Rate limit error: %v", err)
+		return true
+	}
+	if wait > 0 {
+		AbortTooManyRequests(c, wait, apierror.CodeRateLimited, "Rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// AbortTooManyRequests rejects a request with 429 and a Retry-After header
+// in whole seconds.
+func AbortTooManyRequests(c *gin.Context, wait time.Duration, code apierror.Code, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	apierror.Respond(c, http.StatusTooManyRequests, code, message)
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiVersionKey = "api_version"
+
+// When the unversioned API was superseded by /api/v1
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// APIVersion marks the routes of a group as belonging to a version of the
+// API, which decides the shape of their responses.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// Version returns the API version of the route, or 0 for the legacy,
+// unversioned routes.
+func Version(c *gin.Context) int {
+	return c.GetInt(apiVersionKey)
+}
+
+// Deprecated announces that the legacy routes under prefix are deprecated
+// (RFC 9745) and links each one to its counterpart under successor.
+func Deprecated(prefix, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", legacyDeprecatedAt.Unix()))
+		path := successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, path))
+		c.Next()
+	}
+}
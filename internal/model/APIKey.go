@@ -0,0 +1,43 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type Scope string
+
+const (
+	// ScopeRead allows reading pastes and runs
+	ScopeRead Scope = "read"
+	// ScopeSubmit allows creating, running and managing pastes
+	ScopeSubmit Scope = "submit"
+	// ScopeAdmin allows everything, including managing other users
+	ScopeAdmin Scope = "admin"
+)
+
+func (s Scope) IsValid() bool {
+	return s == ScopeRead || s == ScopeSubmit || s == ScopeAdmin
+}
+
+// APIKey authenticates a user. Only the hash of the key is stored; the key
+// itself is shown once, when it is created.
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	KeyHash   string     `json:"-"`
+	Scopes    []Scope    `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key grants scope. The admin scope grants
+// every scope.
+func (k *APIKey) HasScope(scope Scope) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
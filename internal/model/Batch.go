@@ -0,0 +1,13 @@
+package model
+
+import (
+	"time"
+)
+
+// Batch groups pastes submitted together, such as the files of a homework
+// set, so that their progress can be followed at once.
+type Batch struct {
+	ID        string
+	OwnerID   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,5 @@
+package model
+
+type BatchRequest struct {
+	Items []SubmitRequest `json:"items" binding:"required"`
+}
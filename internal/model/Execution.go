@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+)
+
+// Execution is one run of a paste's code. A paste may be run many times
+// with different stdin or limits. The initial run requested on submission
+// shares the paste's ID and its result is mirrored onto the paste.
+type Execution struct {
+	ID              string      `json:"id"`
+	PasteID         string      `json:"paste_id"`
+	Code            string      `json:"-"`
+	Language        string      `json:"-"`
+	Stdin           string      `json:"stdin"`
+	TimeLimit       float32     `json:"time_limit"`
+	MemoryLimit     int         `json:"memory_limit"`
+	Status          PasteStatus `json:"status"`
+	Stdout          string      `json:"stdout"`
+	Stderr          string      `json:"stderr"`
+	StdoutTruncated bool        `json:"stdout_truncated"`
+	StderrTruncated bool        `json:"stderr_truncated"`
+	CompileLog      string      `json:"compile_log"`
+	ExecutionTimeMs int         `json:"execution_time_ms"`
+	MemoryUsageKb   int         `json:"memory_usage_kb"`
+	CacheHit        bool        `json:"cache_hit"`
+	Toolchain       string      `json:"toolchain"`
+	BackEnd         string      `json:"backend"`
+	// Exit status of the program; nil if it was not run or did not exit
+	// by itself, e.g. on a compile error or timeout
+	ExitCode *int `json:"exit_code,omitempty"`
+	// User charged for the run's CPU time; empty for anonymous runs
+	RequestedBy string    `json:"requested_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsInitial reports whether e is the run requested when the paste was
+// submitted.
+func (e *Execution) IsInitial() bool {
+	return e.ID == e.PasteID
+}
+
+// ApplyTo copies the result of e onto p.
+func (e *Execution) ApplyTo(p *Paste) {
+	p.Status = e.Status
+	p.Stdout = e.Stdout
+	p.Stderr = e.Stderr
+	p.StdoutTruncated = e.StdoutTruncated
+	p.StderrTruncated = e.StderrTruncated
+	p.CompileLog = e.CompileLog
+	p.ExecutionTimeMs = e.ExecutionTimeMs
+	p.MemoryUsageKb = e.MemoryUsageKb
+	p.ExitCode = e.ExitCode
+	p.CacheHit = e.CacheHit
+	p.Toolchain = e.Toolchain
+	p.BackEnd = e.BackEnd
+}
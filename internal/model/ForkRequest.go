@@ -0,0 +1,10 @@
+package model
+
+// ForkRequest creates a new revision of a paste. Nil fields are inherited
+// from the parent.
+type ForkRequest struct {
+	Code     *string `json:"code"`
+	Language *string `json:"language"`
+	Stdin    *string `json:"stdin"`
+	Run      bool    `json:"run"`
+}
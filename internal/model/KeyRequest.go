@@ -0,0 +1,14 @@
+package model
+
+// KeyRequest creates an API key. The scopes may not exceed those of the key
+// used to make the request.
+type KeyRequest struct {
+	Name   string  `json:"name" binding:"required"`
+	Scopes []Scope `json:"scopes" binding:"required,min=1"`
+}
+
+// UserRequest creates a user together with a first API key.
+type UserRequest struct {
+	Name   string  `json:"name" binding:"required"`
+	Scopes []Scope `json:"scopes"`
+}
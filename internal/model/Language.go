@@ -0,0 +1,60 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Language describes a supported language and how its sources are built.
+type Language struct {
+	Name string
+	// Source file extensions, including the dot. The first one is used to
+	// name downloaded sources.
+	Extensions []string
+	Flags      []string
+}
+
+var Languages = []Language{
+	{Name: "c++20", Extensions: []string{".cpp", ".cc", ".cxx"}, Flags: []string{"-std=c++20"}},
+}
+
+func LookupLanguage(name string) (Language, bool) {
+	for _, lang := range Languages {
+		if lang.Name == name {
+			return lang, true
+		}
+	}
+	return Language{}, false
+}
+
+// LookupExtension returns the language whose sources use the extension of
+// filename.
+func LookupExtension(filename string) (Language, bool) {
+	ext := strings.ToLower(path.Ext(filename))
+	if ext == "" {
+		return Language{}, false
+	}
+	for _, lang := range Languages {
+		if slices.Contains(lang.Extensions, ext) {
+			return lang, true
+		}
+	}
+	return Language{}, false
+}
+
+// ExecutionHash identifies an execution by its code, language, compiler
+// flags and stdin. Together with the toolchain it determines the result of
+// a deterministic program.
+func ExecutionHash(code, language, stdin string) string {
+	lang, _ := LookupLanguage(language)
+
+	h := sha256.New()
+	for _, part := range []string{code, language, strings.Join(lang.Flags, " "), stdin} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
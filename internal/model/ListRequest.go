@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// ListRequest holds the query parameters of a paste listing.
+type ListRequest struct {
+	Language      string      `form:"language"`
+	Status        PasteStatus `form:"status"`
+	Owner         string      `form:"owner"`
+	BackEnd       string      `form:"backend"`
+	CreatedAfter  *time.Time  `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time  `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Query         string      `form:"q"`
+	Cursor        string      `form:"cursor"`
+	Limit         int         `form:"limit" binding:"omitempty,min=1,max=100"`
+}
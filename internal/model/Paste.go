@@ -5,17 +5,39 @@ import (
 )
 
 type Paste struct {
-	ID              string
-	Code            string    `json:"code"`
-	Language        string    `json:"language"`
-	Stdin           string    `json:"stdin"`
-	Stdout          string    `json:"stdout"`
-	Stderr          string    `json:"stderr"`
-	Status          PasteStatus `json:"status"`
-	CompileLog      string    `json:"compile_log"`
-	ExecutionTimeMs int       `json:"execution_time_ms"`
-	MemoryUsageKb   int       `json:"memory_usage_kb"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	BackEnd         string    `json:"backend"`
+	ID               string
+	Code             string      `json:"code"`
+	Language         string      `json:"language"`
+	Stdin            string      `json:"stdin"`
+	Stdout           string      `json:"stdout"`
+	Stderr           string      `json:"stderr"`
+	StdoutTruncated  bool        `json:"stdout_truncated"`
+	StderrTruncated  bool        `json:"stderr_truncated"`
+	Status           PasteStatus `json:"status"`
+	CompileLog       string      `json:"compile_log"`
+	ExecutionTimeMs  int         `json:"execution_time_ms"`
+	MemoryUsageKb    int         `json:"memory_usage_kb"`
+	ExitCode         *int        `json:"exit_code,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+	BackEnd          string      `json:"backend"`
+	CacheHit         bool        `json:"cache_hit"`
+	Toolchain        string      `json:"toolchain"`
+	CachedFrom       string      `json:"cached_from,omitempty"`
+	ParentID         string      `json:"parent_id,omitempty"`
+	Revision         int         `json:"revision"`
+	ExpiresAt        *time.Time  `json:"expires_at,omitempty"`
+	BurnAfterReading bool        `json:"burn_after_reading"`
+	Visibility       Visibility  `json:"visibility"`
+	OwnerID          string      `json:"owner_id,omitempty"`
+	PasswordHash     string      `json:"-"`
+	ExecutionHash    string      `json:"-"`
+	TokenHash        string      `json:"-"`
+	// Notified when the initial run is finished, see WebhookDelivery
+	CallbackURL string `json:"-"`
+}
+
+// IsExpired reports whether p is past its expiry time at now.
+func (p *Paste) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
 }
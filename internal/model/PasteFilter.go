@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// PasteFilter selects pastes for listing. Zero fields do not filter. Only
+// public pastes are listed, except that the viewer's own pastes are listed
+// whatever their visibility. Expired and burn-after-reading pastes are never
+// listed.
+type PasteFilter struct {
+	Language      string
+	Status        PasteStatus
+	OwnerID       string
+	BackEnd       string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Full-text query over the code
+	Query    string
+	ViewerID string
+	// Position after which the page starts, in listing order
+	After *PasteCursor
+	Limit int
+}
+
+// PasteCursor is a position in a listing, which is ordered by creation
+// time and then ID, newest first.
+type PasteCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
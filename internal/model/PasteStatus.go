@@ -3,12 +3,28 @@ package model
 type PasteStatus string
 
 const (
-	StatusPending           PasteStatus = "pending"
-	StatusRunning           PasteStatus = "running"
-	StatusCompileError      PasteStatus = "compile error"
-	StatusRuntimeError      PasteStatus = "runtime error"
-	StatusTimeLimitExceed   PasteStatus = "time limit exceeded"
-	StatusMemoryLimitExceed PasteStatus = "memory limit exceeded"
-	StatusUnknownError      PasteStatus = "unknown error"
-	StatusCompleted         PasteStatus = "completed"
+	StatusPending             PasteStatus = "pending"
+	StatusRunning             PasteStatus = "running"
+	StatusCompileError        PasteStatus = "compile error"
+	StatusRuntimeError        PasteStatus = "runtime error"
+	StatusTimeLimitExceed     PasteStatus = "time limit exceeded"
+	StatusMemoryLimitExceed   PasteStatus = "memory limit exceeded"
+	StatusOutputLimitExceed   PasteStatus = "output limit exceeded"
+	StatusResourceLimitExceed PasteStatus = "resource limit exceeded"
+	StatusUnknownError        PasteStatus = "unknown error"
+	StatusCompleted           PasteStatus = "completed"
 )
+
+// DeterministicStatuses are the outcomes that depend only on the code, the
+// input and the toolchain, so an earlier result can stand in for a rerun.
+// Runtime errors are left out, as signals, races or memory use close to
+// the limit may make them fail differently or not at all the next time.
+var DeterministicStatuses = []PasteStatus{
+	StatusCompleted,
+	StatusCompileError,
+}
+
+// IsTerminal reports whether no further change to the result is expected.
+func (s PasteStatus) IsTerminal() bool {
+	return s != StatusPending && s != StatusRunning
+}
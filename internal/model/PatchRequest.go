@@ -0,0 +1,13 @@
+package model
+
+import (
+	"time"
+)
+
+// PatchRequest changes the settings of a paste. Fields left out are kept.
+type PatchRequest struct {
+	ExpiresIn  string      `json:"expires_in"`
+	ExpiresAt  *time.Time  `json:"expires_at"`
+	Visibility *Visibility `json:"visibility"`
+	Password   *string     `json:"password"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// RunRequest runs the stored code of a paste again. Zero limits fall back
+// to the worker's defaults; larger limits are capped by them.
+type RunRequest struct {
+	Stdin       string  `json:"stdin"`
+	TimeLimit   float32 `json:"time_limit" binding:"gte=0"`
+	MemoryLimit int     `json:"memory_limit" binding:"gte=0"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// Session is a browser login. Like API keys, only the hash of the session
+// token is stored.
+type Session struct {
+	TokenHash string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SessionScopes are the scopes granted to a browser session.
+var SessionScopes = []Scope{ScopeRead, ScopeSubmit}
+
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -1,9 +1,20 @@
 package model
 
+import (
+	"time"
+)
+
 type SubmitRequest struct {
-	Code     string `json:"code" binding:"required"`
-	Language string `json:"language" binding:"required"`
-	Run      bool   `json:"run"`
-	Stdin    string `json:"stdin"`
-	BackEnd  string `json:"backend"`
+	Code             string     `json:"code" binding:"required"`
+	Language         string     `json:"language" binding:"required"`
+	Run              bool       `json:"run"`
+	Stdin            string     `json:"stdin"`
+	BackEnd          string     `json:"backend"`
+	ExpiresIn        string     `json:"expires_in"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	BurnAfterReading bool       `json:"burn_after_reading"`
+	Visibility       Visibility `json:"visibility"`
+	Password         string     `json:"password"`
+	// Receives a signed POST once the run is finished; requires run
+	CallbackURL string `json:"callback_url"`
 }
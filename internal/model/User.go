@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Identity at the OIDC provider, for users who signed in through SSO
+	OIDCIssuer  string `json:"-"`
+	OIDCSubject string `json:"-"`
+}
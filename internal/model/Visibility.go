@@ -0,0 +1,23 @@
+package model
+
+type Visibility string
+
+const (
+	// VisibilityPublic pastes can be read by anyone and appear in listings
+	VisibilityPublic Visibility = "public"
+	// VisibilityUnlisted pastes can be read by anyone with the ID
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate pastes can only be read by their owner
+	VisibilityPrivate Visibility = "private"
+	// VisibilityPassword pastes can be read by anyone with the ID and password
+	VisibilityPassword Visibility = "password"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate, VisibilityPassword:
+		return true
+	default:
+		return false
+	}
+}
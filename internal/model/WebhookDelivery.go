@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+type WebhookStatus string
+
+const (
+	WebhookPending   WebhookStatus = "pending"
+	WebhookDelivered WebhookStatus = "delivered"
+	WebhookFailed    WebhookStatus = "failed"
+)
+
+// WebhookDelivery is the callback of a paste, queued when its initial run
+// is finished. It also records the outcome of the latest attempt.
+type WebhookDelivery struct {
+	ID            string
+	PasteID       string
+	URL           string
+	Status        WebhookStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	// HTTP status of the latest response, 0 if there was none
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
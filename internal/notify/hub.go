@@ -0,0 +1,63 @@
+// Package notify lets requests wait for a paste to change without polling
+// the database. A single feed of change notifications from the repository
+// is fanned out to the waiters in this process.
+package notify
+
+import "sync"
+
+// Hub delivers change notifications to the subscribers of a paste. It is
+// safe for concurrent use.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[chan struct{}]struct{})}
+}
+
+// Subscribe returns a channel that receives a value after the paste with
+// the given ID changes, and a function that ends the subscription.
+// Notifications arriving while one is pending are merged into it.
+func (h *Hub) Subscribe(id string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subscribers[id] == nil {
+		h.subscribers[id] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[id][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[id], ch)
+		if len(h.subscribers[id]) == 0 {
+			delete(h.subscribers, id)
+		}
+	}
+}
+
+// Publish notifies the subscribers of a paste. An empty ID notifies every
+// subscriber, for when notifications may have been lost.
+func (h *Hub) Publish(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if id == "" {
+		for _, subscribers := range h.subscribers {
+			wake(subscribers)
+		}
+		return
+	}
+	wake(h.subscribers[id])
+}
+
+func wake(subscribers map[chan struct{}]struct{}) {
+	for ch := range subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
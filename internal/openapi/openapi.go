@@ -0,0 +1,359 @@
+// Package openapi describes the API as an OpenAPI 3 document. The routes are
+// listed here by hand, while the schemas are generated from the Go types
+// the handlers bind and return, so they follow the models as they change.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"runbin/internal/dto"
+	"runbin/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type authLevel int
+
+const (
+	// Anyone may call the operation, and credentials are ignored
+	authNone authLevel = iota
+	// Credentials are optional but may be needed for restricted pastes
+	authOptional
+	// An API key or session is required
+	authRequired
+)
+
+// operation describes one route.
+type operation struct {
+	method  string
+	path    string // in gin syntax, e.g. /api/pastes/:id
+	tag     string
+	summary string
+
+	auth authLevel
+	// The management token of the paste is accepted instead of credentials
+	manage bool
+	// Password-protected pastes need the X-Paste-Password header
+	password bool
+
+	query        any                 // struct with form tags
+	pathEnums    map[string][]string // allowed values of path parameters
+	body         any                 // JSON request body
+	bodyOptional bool
+	// An upload, with a multipart form or the raw code as the body
+	upload bool
+
+	status      int
+	response    any    // JSON response body; the data of the envelope in /api/v1
+	legacy      any    // JSON response body of the deprecated route
+	contentType string // non-JSON response body
+
+	versioned bool
+	// The response carries a cursor in its metadata
+	paged bool
+	// Set on the operations returned by expanded
+	envelope   bool
+	deprecated bool
+}
+
+// operations lists the routes. Versioned operations have paths relative to
+// the API version and are registered both under /api/v1 and, deprecated,
+// under /api.
+var operations = []operation{
+	{method: http.MethodGet, path: "/pastes", versioned: true, paged: true, tag: "pastes", summary: "List and search public pastes",
+		auth: authOptional, query: model.ListRequest{}, status: http.StatusOK, response: []*dto.Paste{}, legacy: pasteList{}},
+	{method: http.MethodPost, path: "/pastes", versioned: true, tag: "pastes", summary: "Submit a paste",
+		auth: authOptional, body: model.SubmitRequest{}, status: http.StatusAccepted, response: dto.Created{}, legacy: created{}},
+	{method: http.MethodGet, path: "/pastes/:id", versioned: true, tag: "pastes", summary: "Get a paste and its latest run",
+		auth: authOptional, manage: true, password: true, query: getQuery{}, status: http.StatusOK, response: dto.Paste{}, legacy: pasteResult{}},
+	{method: http.MethodPatch, path: "/pastes/:id", versioned: true, tag: "pastes", summary: "Change the expiry or visibility of a paste",
+		auth: authOptional, manage: true, body: model.PatchRequest{}, status: http.StatusOK, response: dto.Paste{}, legacy: updated{}},
+	{method: http.MethodDelete, path: "/pastes/:id", versioned: true, tag: "pastes", summary: "Delete a paste",
+		auth: authOptional, manage: true, status: http.StatusNoContent},
+	{method: http.MethodPost, path: "/pastes/:id/fork", versioned: true, tag: "pastes", summary: "Fork a paste",
+		auth: authOptional, manage: true, password: true, body: model.ForkRequest{}, bodyOptional: true,
+		status: http.StatusAccepted, response: dto.Created{}, legacy: created{}},
+	{method: http.MethodGet, path: "/pastes/:id/history", versioned: true, tag: "pastes", summary: "Get the revision history of a paste",
+		auth: authOptional, manage: true, password: true, status: http.StatusOK, response: []*dto.Paste{}, legacy: history{}},
+	{method: http.MethodGet, path: "/pastes/:id/raw", versioned: true, tag: "pastes", summary: "Download the source of a paste",
+		auth: authOptional, manage: true, password: true, status: http.StatusOK, contentType: "text/plain"},
+	{method: http.MethodGet, path: "/pastes/:id/raw/:stream", versioned: true, tag: "pastes", summary: "Download an output of a paste",
+		auth: authOptional, manage: true, password: true,
+		pathEnums: map[string][]string{"stream": {"stdout", "stderr", "compile_log"}},
+		status:    http.StatusOK, contentType: "text/plain"},
+	{method: http.MethodGet, path: "/pastes/:id/archive", versioned: true, tag: "pastes", summary: "Download a paste and all its outputs as a zip archive",
+		auth: authOptional, manage: true, password: true, status: http.StatusOK, contentType: "application/zip"},
+	{method: http.MethodPost, path: "/pastes/:id/runs", versioned: true, tag: "runs", summary: "Run a paste again",
+		auth: authOptional, manage: true, password: true, body: model.RunRequest{}, bodyOptional: true,
+		status: http.StatusAccepted, response: dto.RunCreated{}, legacy: runCreated{}},
+	{method: http.MethodGet, path: "/pastes/:id/runs", versioned: true, tag: "runs", summary: "List the runs of a paste",
+		auth: authOptional, manage: true, password: true, status: http.StatusOK, response: []*dto.Run{}, legacy: runList{}},
+	{method: http.MethodGet, path: "/pastes/:id/runs/:run_id", versioned: true, tag: "runs", summary: "Get a run of a paste",
+		auth: authOptional, manage: true, password: true, status: http.StatusOK, response: dto.Run{}, legacy: model.Execution{}},
+	{method: http.MethodGet, path: "/languages", versioned: true, tag: "pastes", summary: "List the supported languages",
+		status: http.StatusOK, response: []dto.Language{}, legacy: languageList{}},
+	{method: http.MethodPost, path: "/batches", versioned: true, tag: "batches", summary: "Submit several pastes at once",
+		auth: authOptional, body: model.BatchRequest{}, status: http.StatusAccepted, response: dto.BatchCreated{}, legacy: dto.BatchCreated{}},
+	{method: http.MethodGet, path: "/batches/:id", versioned: true, tag: "batches", summary: "Get the progress of a batch",
+		auth: authOptional, status: http.StatusOK, response: dto.Batch{}, legacy: dto.Batch{}},
+	{method: http.MethodPost, path: "/", tag: "pastes", summary: "Upload a paste from a terminal",
+		auth: authOptional, query: uploadQuery{}, upload: true, status: http.StatusAccepted, contentType: "text/plain"},
+
+	{method: http.MethodPost, path: "/users", versioned: true, tag: "users", summary: "Create a user (admin)",
+		auth: authRequired, body: model.UserRequest{}, status: http.StatusCreated, response: dto.CreatedUser{}, legacy: createdUser{}},
+	{method: http.MethodGet, path: "/users/me", versioned: true, tag: "users", summary: "Get the authenticated user",
+		auth: authRequired, status: http.StatusOK, response: dto.Me{}, legacy: me{}},
+	{method: http.MethodGet, path: "/users/me/pastes", versioned: true, tag: "users", summary: "List the pastes of the authenticated user",
+		auth: authRequired, status: http.StatusOK, response: []*dto.Paste{}, legacy: userPastes{}},
+	{method: http.MethodGet, path: "/keys", versioned: true, tag: "users", summary: "List API keys",
+		auth: authRequired, status: http.StatusOK, response: []dto.APIKey{}, legacy: keyList{}},
+	{method: http.MethodPost, path: "/keys", versioned: true, tag: "users", summary: "Create an API key",
+		auth: authRequired, body: model.KeyRequest{}, status: http.StatusCreated, response: dto.CreatedKey{}, legacy: createdKey{}},
+	{method: http.MethodDelete, path: "/keys/:id", versioned: true, tag: "users", summary: "Revoke an API key",
+		auth: authRequired, status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/api/auth/login", tag: "auth", summary: "Sign in through the OIDC provider",
+		status: http.StatusFound},
+	{method: http.MethodGet, path: "/api/auth/callback", tag: "auth", summary: "Complete an OIDC sign-in",
+		status: http.StatusFound},
+	{method: http.MethodPost, path: "/api/auth/logout", tag: "auth", summary: "Sign out",
+		status: http.StatusNoContent},
+
+	{method: http.MethodGet, path: "/api/openapi.json", tag: "meta", summary: "Get this document",
+		status: http.StatusOK, contentType: "application/json"},
+}
+
+// expanded returns one operation per registered route, with full paths.
+// The deprecated routes come last.
+func expanded() []operation {
+	var out, deprecated []operation
+	for _, op := range operations {
+		if !op.versioned {
+			out = append(out, op)
+			continue
+		}
+		v1 := op
+		v1.path = "/api/v1" + op.path
+		v1.envelope = true
+		legacy := op
+		legacy.path = "/api" + op.path
+		legacy.response = op.legacy
+		legacy.deprecated = true
+		out = append(out, v1)
+		deprecated = append(deprecated, legacy)
+	}
+	return append(out, deprecated...)
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// openAPIPath converts a gin route path to OpenAPI syntax.
+func openAPIPath(path string) string {
+	return pathParam.ReplaceAllString(path, "{$1}")
+}
+
+// Document returns the OpenAPI document of the API.
+func Document() map[string]any {
+	s := newSchemas()
+	paths := make(map[string]map[string]any)
+	for _, op := range expanded() {
+		path := openAPIPath(op.path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(op.method)] = op.document(s)
+	}
+	s.of(reflect.TypeFor[errorResponse]())
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "RunBin API",
+			"version": "1.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"apiKey":          map[string]any{"type": "http", "scheme": "bearer"},
+				"session":         map[string]any{"type": "apiKey", "in": "cookie", "name": "runbin_session"},
+				"managementToken": map[string]any{"type": "apiKey", "in": "header", "name": "X-Management-Token"},
+			},
+			"responses": map[string]any{
+				"Error": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": map[string]any{"$ref": "#/components/schemas/ErrorResponse"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (op *operation) document(s *schemas) map[string]any {
+	doc := map[string]any{
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"operationId": operationID(op),
+	}
+	if op.deprecated {
+		doc["deprecated"] = true
+	}
+
+	var params []any
+	for _, match := range pathParam.FindAllStringSubmatch(op.path, -1) {
+		schema := map[string]any{"type": "string"}
+		if values, ok := op.pathEnums[match[1]]; ok {
+			schema["enum"] = values
+		}
+		params = append(params, map[string]any{
+			"name": match[1], "in": "path", "required": true, "schema": schema,
+		})
+	}
+	if op.query != nil {
+		t := reflect.TypeOf(op.query)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			params = append(params, map[string]any{
+				"name": f.Tag.Get("form"), "in": "query", "schema": s.of(f.Type),
+			})
+		}
+	}
+	if op.password {
+		params = append(params, map[string]any{
+			"name": "X-Paste-Password", "in": "header", "schema": map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		doc["parameters"] = params
+	}
+
+	switch {
+	case op.body != nil:
+		doc["requestBody"] = map[string]any{
+			"required": !op.bodyOptional,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": s.of(reflect.TypeOf(op.body))},
+			},
+		}
+	case op.upload:
+		doc["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"multipart/form-data": map[string]any{"schema": s.object(reflect.TypeFor[uploadForm]())},
+				"text/plain":          map[string]any{"schema": map[string]any{"type": "string"}},
+			},
+		}
+	}
+
+	var security []any
+	switch op.auth {
+	case authOptional:
+		security = append(security, map[string]any{})
+		fallthrough
+	case authRequired:
+		security = append(security,
+			map[string]any{"apiKey": []string{}},
+			map[string]any{"session": []string{}})
+	}
+	if op.manage {
+		security = append(security, map[string]any{"managementToken": []string{}})
+	}
+	if security == nil {
+		security = []any{}
+	}
+	doc["security"] = security
+
+	success := map[string]any{"description": http.StatusText(op.status)}
+	switch {
+	case op.response != nil:
+		schema := s.of(reflect.TypeOf(op.response))
+		if op.envelope {
+			properties := map[string]any{"data": schema}
+			if op.paged {
+				properties["meta"] = s.of(reflect.TypeFor[dto.Meta]())
+			}
+			schema = map[string]any{"type": "object", "required": []string{"data"}, "properties": properties}
+		}
+		success["content"] = map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		}
+	case op.contentType != "":
+		schema := map[string]any{"type": "string"}
+		if op.contentType == "application/zip" {
+			schema["format"] = "binary"
+		}
+		if op.contentType == "application/json" {
+			schema = map[string]any{"type": "object"}
+		}
+		success["content"] = map[string]any{op.contentType: map[string]any{"schema": schema}}
+	}
+	doc["responses"] = map[string]any{
+		strconv.Itoa(op.status): success,
+		"default":               map[string]any{"$ref": "#/components/responses/Error"},
+	}
+	return doc
+}
+
+// operationID names an operation after its method and path, e.g.
+// getPastesIdRuns for GET /api/v1/pastes/:id/runs and legacyGetPastesIdRuns
+// for its deprecated alias.
+func operationID(op *operation) string {
+	id := strings.ToLower(op.method)
+	path := strings.TrimPrefix(op.path, "/api")
+	path = strings.TrimPrefix(path, "/v1")
+	for _, part := range strings.Split(path, "/") {
+		part = strings.TrimPrefix(part, ":")
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '_' || r == '.' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	if id == strings.ToLower(op.method) {
+		id += "Root"
+	}
+	if op.deprecated {
+		id = "legacy" + strings.ToUpper(id[:1]) + id[1:]
+	}
+	return id
+}
+
+var documentJSON = sync.OnceValue(func() []byte {
+	data, err := json.Marshal(Document())
+	if err != nil {
+		panic(fmt.Sprintf("marshal OpenAPI document error: %v", err))
+	}
+	return data
+})
+
+// Handler serves the document.
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", documentJSON())
+}
+
+// Undocumented returns the routes that are not described by the document,
+// as "METHOD path".
+func Undocumented(routes gin.RoutesInfo) []string {
+	documented := make(map[string]bool)
+	for _, op := range expanded() {
+		documented[op.method+" "+op.path] = true
+	}
+
+	var missing []string
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"time"
+
+	"runbin/internal/apierror"
+	"runbin/internal/model"
+)
+
+// The types below describe the bodies that handlers build with gin.H, and
+// the parameters they read by hand. They exist only to generate schemas.
+
+type errorResponse struct {
+	Error apierror.Error `json:"error"`
+}
+
+type created struct {
+	Message         string `json:"message"`
+	PasteID         string `json:"paste_id"`
+	URL             string `json:"url"`
+	ManagementToken string `json:"management_token"`
+	CachedFrom      string `json:"cached_from,omitempty"`
+}
+
+type pasteResult struct {
+	model.Paste
+	LatestRun *model.Execution `json:"latest_run,omitempty"`
+	RunsURL   string           `json:"runs_url"`
+}
+
+type pasteList struct {
+	Pastes     []*model.Paste `json:"pastes"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type updated struct {
+	Message    string           `json:"message"`
+	PasteID    string           `json:"paste_id"`
+	ExpiresAt  *time.Time       `json:"expires_at"`
+	Visibility model.Visibility `json:"visibility"`
+}
+
+type history struct {
+	History []*model.Paste `json:"history"`
+}
+
+type runCreated struct {
+	Message string `json:"message"`
+	RunID   string `json:"run_id"`
+	URL     string `json:"url"`
+}
+
+type runList struct {
+	Runs []*model.Execution `json:"runs"`
+}
+
+type languageList struct {
+	Languages []string `json:"languages"`
+}
+
+type createdUser struct {
+	User   *model.User   `json:"user"`
+	Key    *model.APIKey `json:"key"`
+	APIKey string        `json:"api_key"`
+}
+
+type me struct {
+	User *model.User   `json:"user"`
+	Key  *model.APIKey `json:"key"`
+}
+
+type userPastes struct {
+	Pastes []*model.Paste `json:"pastes"`
+}
+
+type keyList struct {
+	Keys []*model.APIKey `json:"keys"`
+}
+
+type createdKey struct {
+	Key    *model.APIKey `json:"key"`
+	APIKey string        `json:"api_key"`
+}
+
+// getQuery holds the parameters of getting a paste.
+type getQuery struct {
+	// A duration such as 30s to wait for the result, capped by app.maxwait
+	Wait string `form:"wait"`
+}
+
+// uploadQuery holds the parameters of a raw upload.
+type uploadQuery struct {
+	Lang       string           `form:"lang"`
+	Name       string           `form:"name"`
+	Stdin      string           `form:"stdin"`
+	Run        bool             `form:"run"`
+	ExpiresIn  string           `form:"expires_in"`
+	Visibility model.Visibility `form:"visibility"`
+}
+
+// uploadForm is the multipart form of an upload.
+type uploadForm struct {
+	Code       string           `json:"code" format:"binary" binding:"required"`
+	Stdin      string           `json:"stdin" format:"binary"`
+	Lang       string           `json:"lang"`
+	Run        bool             `json:"run"`
+	ExpiresIn  string           `json:"expires_in"`
+	Visibility model.Visibility `json:"visibility"`
+}
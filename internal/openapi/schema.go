@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"runbin/internal/apierror"
+	"runbin/internal/model"
+)
+
+// enums lists the values of the string types that are enumerations. They
+// become named schemas of their own.
+var enums = map[reflect.Type][]string{
+	reflect.TypeFor[model.PasteStatus](): enumValues(
+		model.StatusPending,
+		model.StatusRunning,
+		model.StatusCompleted,
+		model.StatusCompileError,
+		model.StatusRuntimeError,
+		model.StatusTimeLimitExceed,
+		model.StatusMemoryLimitExceed,
+		model.StatusOutputLimitExceed,
+		model.StatusResourceLimitExceed,
+		model.StatusUnknownError,
+	),
+	reflect.TypeFor[model.Visibility](): enumValues(
+		model.VisibilityPublic,
+		model.VisibilityUnlisted,
+		model.VisibilityPrivate,
+		model.VisibilityPassword,
+	),
+	reflect.TypeFor[model.Scope](): enumValues(
+		model.ScopeRead,
+		model.ScopeSubmit,
+		model.ScopeAdmin,
+	),
+	reflect.TypeFor[apierror.Code](): enumValues(
+		apierror.CodeInvalidRequest,
+		apierror.CodePayloadTooLarge,
+		apierror.CodeUnsupportedLanguage,
+		apierror.CodeCodeTooLarge,
+		apierror.CodeStdinTooLarge,
+		apierror.CodeInvalidEncoding,
+		apierror.CodeCallbackNotAllowed,
+		apierror.CodeBatchTooLarge,
+		apierror.CodeUnauthorized,
+		apierror.CodeInvalidAPIKey,
+		apierror.CodePasswordRequired,
+		apierror.CodeForbidden,
+		apierror.CodeInsufficientScope,
+		apierror.CodeInvalidPassword,
+		apierror.CodeNotFound,
+		apierror.CodeExpired,
+		apierror.CodeRateLimited,
+		apierror.CodeQuotaExceeded,
+		apierror.CodeInternal,
+	),
+}
+
+// names overrides the schema names of types whose own name is too vague.
+var names = map[reflect.Type]string{
+	reflect.TypeFor[apierror.Code](): "ErrorCode",
+}
+
+func enumValues[T ~string](values ...T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// schemas generates JSON schemas from Go types, following the rules of
+// encoding/json. Named structs and enumerations are collected as
+// components and referred to by name.
+type schemas struct {
+	components map[string]any
+	types      map[string]reflect.Type
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: make(map[string]any), types: make(map[string]reflect.Type)}
+}
+
+// of returns the schema of values of type t.
+func (s *schemas) of(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		return s.of(t.Elem())
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if values, ok := enums[t]; ok {
+		return s.component(t, func() map[string]any {
+			return map[string]any{"type": "string", "enum": values}
+		})
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.component(t, func() map[string]any { return s.object(t) })
+	default:
+		return map[string]any{}
+	}
+}
+
+// component registers the schema built by build under the name of t and
+// returns a reference to it.
+func (s *schemas) component(t reflect.Type, build func() map[string]any) map[string]any {
+	name := componentName(t)
+	if other, ok := s.types[name]; ok && other != t {
+		// The models behind the deprecated routes share the names of
+		// the DTOs, which are documented first
+		name = "Legacy" + name
+	}
+	if _, ok := s.components[name]; !ok {
+		// Reserve the name first, so recursive types terminate
+		s.types[name] = t
+		s.components[name] = nil
+		s.components[name] = build()
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// object returns the schema of a struct. Fields of embedded structs are
+// promoted like encoding/json does, and fields bound with
+// binding:"required" are required.
+func (s *schemas) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	s.fields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *schemas) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema := s.of(f.Type)
+		if format := f.Tag.Get("format"); format != "" {
+			schema["format"] = format
+		}
+		properties[name] = schema
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// componentName names the schema of a named type. Types of this package
+// are unexported and get capitalised.
+func componentName(t reflect.Type) string {
+	if name, ok := names[t]; ok {
+		return name
+	}
+	r, size := utf8.DecodeRuneInString(t.Name())
+	return string(unicode.ToUpper(r)) + t.Name()[size:]
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"runbin/internal/model"
+	"time"
+)
+
+// SaveBatch stores a batch with its pastes and their initial runs, and
+// queues the runs that still have to be executed, all in one transaction.
+// The pastes are kept in the order given.
+func (s *PostgresStore) SaveBatch(b *model.Batch, pastes []*model.Paste, runs []*model.Execution) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin insert of batch %s: %w", b.ID, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO batches (id, owner_id, created_at) VALUES ($1, $2, $3)`,
+		b.ID, b.OwnerID, b.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert batch %s: %w", b.ID, err)
+	}
+
+	for i, p := range pastes {
+		if err := insertPaste(ctx, tx, p); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO batch_items (batch_id, position, paste_id) VALUES ($1, $2, $3)`,
+			b.ID, i, p.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert item %d of batch %s: %w", i, b.ID, err)
+		}
+	}
+
+	for _, e := range runs {
+		if err := insertExecution(ctx, tx, e); err != nil {
+			return err
+		}
+		if e.Status.IsTerminal() {
+			if err := queueWebhook(ctx, tx, e.PasteID, e.UpdatedAt); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO queue (id) VALUES ($1)`, e.ID); err != nil {
+			return fmt.Errorf("failed to queue execution %s: %w", e.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetBatch(id string) (*model.Batch, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var b model.Batch
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, owner_id, created_at FROM batches WHERE id = $1`, id).
+		Scan(&b.ID, &b.OwnerID, &b.CreatedAt)
+	if err != nil {
+		return nil, false
+	}
+	return &b, true
+}
+
+// GetBatchPastes returns the pastes of a batch in the order they were
+// submitted. Pastes deleted since are left out.
+func (s *PostgresStore) GetBatchPastes(batchID string) ([]*model.Paste, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+pasteColumns+` FROM pastes p
+		JOIN batch_items i ON i.paste_id = p.id
+		WHERE i.batch_id = $1
+		ORDER BY i.position`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pastes of batch %s: %w", batchID, err)
+	}
+	defer rows.Close()
+
+	var pastes []*model.Paste
+	for rows.Next() {
+		p, err := scanPaste(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pastes of batch %s: %w", batchID, err)
+		}
+		pastes = append(pastes, p)
+	}
+	return pastes, rows.Err()
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel notified by the trigger on pastes, see migration 0017
+const pasteChangesChannel = "paste_changes"
+
+// WatchPastes listens for the notifications sent when the status of a
+// paste changes. All waiters of the process share this one connection.
+func (s *PostgresStore) WatchPastes(ctx context.Context, changed func(id string)) error {
+	listener := pq.NewListener(s.connStr, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Paste change listener error: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(pasteChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen for paste changes: %w", err)
+	}
+
+	// Ping now and then, so a silently dropped connection is noticed
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect, after which
+			// notifications sent in between are gone
+			if n == nil {
+				changed("")
+				continue
+			}
+			changed(n.Extra)
+		case <-ping.C:
+			go listener.Ping()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Take implements RateLimitStore. The bucket row is locked for the duration
+// of the transaction, so concurrent replicas see a consistent count.
+func (s *PostgresStore) Take(key string, n int, rate float64, burst int, now time.Time) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin rate limit of %s: %w", key, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING`, key, float64(burst), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create bucket %s: %w", key, err)
+	}
+
+	var tokens float64
+	var updatedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key).
+		Scan(&tokens, &updatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bucket %s: %w", key, err)
+	}
+
+	// Clocks of replicas may disagree slightly; never refill backwards
+	if updatedAt.After(now) {
+		now = updatedAt
+	}
+	tokens, wait := refill(tokens, updatedAt, n, rate, burst, now)
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2 WHERE key = $3`,
+		tokens, now, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update bucket %s: %w", key, err)
+	}
+
+	return wait, tx.Commit()
+}
+
+func (s *PostgresStore) DeleteIdleBuckets(before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete idle buckets: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"runbin/internal/model"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresStore struct {
-	db *sql.DB
+	db      *sql.DB
+	connStr string
 }
 
 func NewPostgresStore(connStr string) (*PostgresStore, error) {
@@ -27,39 +30,31 @@ func NewPostgresStore(connStr string) (*PostgresStore, error) {
 		return nil, fmt.Errorf("database ping failed: %w", err)
 	}
 
-	return &PostgresStore{db: db}, nil
+	return &PostgresStore{db: db, connStr: connStr}, nil
 }
 
-func (s *PostgresStore) Save(p *model.Paste) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO pastes (
-			id, code, created_at, status,
-			language, stdin, stdout, stderr,
-			execution_time_ms, memory_usage_kb, updated_at, backend,
-			compile_log
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
-		p.ID, p.Code, p.CreatedAt, p.Status,
-		p.Language, p.Stdin, p.Stdout, p.Stderr,
-		p.ExecutionTimeMs, p.MemoryUsageKb, p.UpdatedAt, p.BackEnd, p.CompileLog)
+// pasteColumns lists the columns read by scanPaste, in order.
+const pasteColumns = `
+	id, code, created_at, status,
+	language, stdin, stdout, stderr,
+	execution_time_ms, memory_usage_kb, updated_at, backend,
+	compile_log, stdout_truncated, stderr_truncated, cache_hit,
+	execution_hash, toolchain, cached_from, parent_id, revision,
+	expires_at, burn_after_reading, token_hash, visibility, password_hash,
+	owner_id, exit_code, callback_url`
 
-	return err
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (s *PostgresStore) GetByID(id string) (*model.Paste, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// execer is a *sql.DB or a *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
 
+func scanPaste(row rowScanner) (*model.Paste, error) {
 	var p model.Paste
-	err := s.db.QueryRowContext(ctx,
-		`SELECT 
-			id, code, created_at, status,
-			language, stdin, stdout, stderr,
-			execution_time_ms, memory_usage_kb, updated_at, backend, 
-			compile_log
-		FROM pastes WHERE id = $1`, id).Scan(
+	err := row.Scan(
 		&p.ID,
 		&p.Code,
 		&p.CreatedAt,
@@ -72,15 +67,348 @@ func (s *PostgresStore) GetByID(id string) (*model.Paste, bool) {
 		&p.MemoryUsageKb,
 		&p.UpdatedAt,
 		&p.BackEnd,
-		&p.CompileLog)
+		&p.CompileLog,
+		&p.StdoutTruncated,
+		&p.StderrTruncated,
+		&p.CacheHit,
+		&p.ExecutionHash,
+		&p.Toolchain,
+		&p.CachedFrom,
+		&p.ParentID,
+		&p.Revision,
+		&p.ExpiresAt,
+		&p.BurnAfterReading,
+		&p.TokenHash,
+		&p.Visibility,
+		&p.PasswordHash,
+		&p.OwnerID,
+		&p.ExitCode,
+		&p.CallbackURL)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *PostgresStore) Save(p *model.Paste) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
+	return insertPaste(ctx, s.db, p)
+}
+
+func insertPaste(ctx context.Context, db execer, p *model.Paste) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO pastes (`+pasteColumns+`
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29)`,
+		p.ID, p.Code, p.CreatedAt, p.Status,
+		p.Language, p.Stdin, p.Stdout, p.Stderr,
+		p.ExecutionTimeMs, p.MemoryUsageKb, p.UpdatedAt, p.BackEnd,
+		p.CompileLog, p.StdoutTruncated, p.StderrTruncated, p.CacheHit,
+		p.ExecutionHash, p.Toolchain, p.CachedFrom, p.ParentID, p.Revision,
+		p.ExpiresAt, p.BurnAfterReading, p.TokenHash, p.Visibility, p.PasswordHash,
+		p.OwnerID, p.ExitCode, p.CallbackURL)
+
+	return err
+}
+
+func (s *PostgresStore) GetByID(id string) (*model.Paste, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	p, err := scanPaste(s.db.QueryRowContext(ctx,
+		`SELECT `+pasteColumns+` FROM pastes WHERE id = $1`, id))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, false
+		return nil, false
+	}
+	return p, true
+}
+
+// FindReusableResult returns the most recent original execution with the
+// given hash that finished deterministically after since, provided it ran
+// on the toolchain most recently used for that language. Only public pastes
+// that can still be read are candidates, as the ID of the source is
+// revealed to whoever submits the same code.
+func (s *PostgresStore) FindReusableResult(hash string, since time.Time) (*model.Paste, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	p, err := scanPaste(s.db.QueryRowContext(ctx,
+		`SELECT `+pasteColumns+` FROM pastes p
+		WHERE execution_hash = $1
+			AND cached_from = ''
+			AND status = ANY($2)
+			AND updated_at >= $3
+			AND visibility = $4
+			AND NOT burn_after_reading
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND toolchain <> ''
+			AND toolchain = (
+				SELECT toolchain FROM pastes
+				WHERE language = p.language AND toolchain <> ''
+				ORDER BY updated_at DESC
+				LIMIT 1
+			)
+		ORDER BY updated_at DESC
+		LIMIT 1`,
+		hash, pq.Array(deterministicStatuses()), since, model.VisibilityPublic))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Find reusable result error: %v", err)
 		}
 		return nil, false
 	}
-	return &p, true
+	return p, true
+}
+
+func (s *PostgresStore) GetHistory(id string) ([]*model.Paste, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`WITH RECURSIVE lineage AS (
+			SELECT * FROM pastes WHERE id = $1
+			UNION ALL
+			SELECT p.* FROM pastes p JOIN lineage l ON p.id = l.parent_id
+		)
+		SELECT `+pasteColumns+` FROM lineage ORDER BY revision`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history of paste %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	var history []*model.Paste
+	for rows.Next() {
+		p, err := scanPaste(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history of paste %s: %w", id, err)
+		}
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
+
+// UpdateSettings stores the owner-editable settings of a paste.
+func (s *PostgresStore) UpdateSettings(p *model.Paste) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE pastes SET
+			expires_at = $1,
+			visibility = $2,
+			password_hash = $3
+		WHERE id = $4`,
+		p.ExpiresAt, p.Visibility, p.PasswordHash, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update settings of paste %s: %w", p.ID, err)
+	}
+	return nil
+}
+
+// Delete removes a paste together with its executions and any of its runs
+// still waiting in the queue.
+func (s *PostgresStore) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin delete of paste %s: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM queue WHERE id IN (SELECT id FROM executions WHERE paste_id = $1)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete queue entries of paste %s: %w", id, err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM pastes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete paste %s: %w", id, err)
+	}
+
+	return tx.Commit()
+}
+
+// Burn expires a burn-after-reading paste that has not expired yet. It
+// reports whether this call was the one that burned it, so exactly one
+// reader gets to see the content.
+func (s *PostgresStore) Burn(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE pastes SET expires_at = NOW()
+		WHERE id = $1
+			AND burn_after_reading
+			AND (expires_at IS NULL OR expires_at > NOW())`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to burn paste %s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to burn paste %s: %w", id, err)
+	}
+	return n == 1, nil
+}
+
+// DeleteExpired removes pastes that expired before now together with their
+// executions and any of their runs still waiting in the queue.
+func (s *PostgresStore) DeleteExpired(now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin expiry cleanup: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM queue WHERE id IN (
+			SELECT e.id FROM executions e JOIN pastes p ON p.id = e.paste_id
+			WHERE p.expires_at <= $1
+		)`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete queue entries of expired pastes: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM pastes WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired pastes: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired pastes: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM batches b WHERE NOT EXISTS (
+			SELECT 1 FROM batch_items i WHERE i.batch_id = b.id
+		)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete emptied batches: %w", err)
+	}
+
+	return n, tx.Commit()
+}
+
+// GetByOwner returns the pastes of a user, newest first.
+func (s *PostgresStore) GetByOwner(ownerID string) ([]*model.Paste, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+pasteColumns+` FROM pastes
+		WHERE owner_id = $1
+		ORDER BY created_at DESC`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pastes of user %s: %w", ownerID, err)
+	}
+	defer rows.Close()
+
+	var pastes []*model.Paste
+	for rows.Next() {
+		p, err := scanPaste(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pastes of user %s: %w", ownerID, err)
+		}
+		pastes = append(pastes, p)
+	}
+	return pastes, rows.Err()
+}
+
+// GetCPUUsage returns the execution time in milliseconds charged to a user
+// on the UTC day of day.
+func (s *PostgresStore) GetCPUUsage(userID string, day time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var ms int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(execution_time_ms), 0) FROM cpu_usage WHERE user_id = $1 AND day = $2`,
+		userID, day.UTC().Format(time.DateOnly)).Scan(&ms)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query CPU usage of user %s: %w", userID, err)
+	}
+	return ms, nil
+}
+
+// ListPastes returns up to filter.Limit pastes matching filter, newest
+// first.
+func (s *PostgresStore) ListPastes(filter model.PasteFilter) ([]*model.Paste, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	conds := []string{
+		"(expires_at IS NULL OR expires_at > NOW())",
+		"NOT burn_after_reading",
+	}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ViewerID != "" {
+		conds = append(conds, "(visibility = 'public' OR owner_id = "+arg(filter.ViewerID)+")")
+	} else {
+		conds = append(conds, "visibility = 'public'")
+	}
+	if filter.Language != "" {
+		conds = append(conds, "language = "+arg(filter.Language))
+	}
+	if filter.Status != "" {
+		conds = append(conds, "status = "+arg(filter.Status))
+	}
+	if filter.OwnerID != "" {
+		conds = append(conds, "owner_id = "+arg(filter.OwnerID))
+	}
+	if filter.BackEnd != "" {
+		conds = append(conds, "backend = "+arg(filter.BackEnd))
+	}
+	if filter.CreatedAfter != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*filter.CreatedBefore))
+	}
+	if filter.Query != "" {
+		conds = append(conds, "search_vector @@ websearch_to_tsquery('simple', "+arg(filter.Query)+")")
+	}
+	if filter.After != nil {
+		conds = append(conds, "(created_at, id) < ("+arg(filter.After.CreatedAt)+", "+arg(filter.After.ID)+")")
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+pasteColumns+` FROM pastes
+		WHERE `+strings.Join(conds, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT `+arg(filter.Limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pastes: %w", err)
+	}
+	defer rows.Close()
+
+	var pastes []*model.Paste
+	for rows.Next() {
+		p, err := scanPaste(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pastes: %w", err)
+		}
+		pastes = append(pastes, p)
+	}
+	return pastes, rows.Err()
+}
+
+func deterministicStatuses() []string {
+	statuses := make([]string, 0, len(model.DeterministicStatuses))
+	for _, status := range model.DeterministicStatuses {
+		statuses = append(statuses, string(status))
+	}
+	return statuses
 }
 
 func (s *PostgresStore) Close() error {
@@ -97,7 +425,7 @@ func (s *PostgresStore) DispatchExecutionTask(id string) error {
 	return err
 }
 
-func (s *PostgresStore) GetTask(ctx context.Context) (*model.Paste, error) {
+func (s *PostgresStore) GetTask(ctx context.Context) (*model.Execution, error) {
 
 	// 原子性地删除并获取队列中最旧的任务ID
 	var taskID string
@@ -119,47 +447,241 @@ func (s *PostgresStore) GetTask(ctx context.Context) (*model.Paste, error) {
 	}
 
 	// 获取完整的任务数据
-	p, ok := s.GetByID(taskID)
+	e, ok := s.GetExecution(taskID)
 
 	if !ok {
-		return nil, fmt.Errorf("failed to get task details: %w", err)
+		return nil, fmt.Errorf("failed to get task details for execution %s", taskID)
 	}
 
-	return p, nil
+	return e, nil
 }
 
-func (s *PostgresStore) Update(p *model.Paste) error {
+// executionColumns lists the columns read by scanExecution, in order. Code
+// and language come from the paste the execution belongs to.
+const executionColumns = `
+	e.id, e.paste_id, p.code, p.language, e.stdin,
+	e.time_limit, e.memory_limit, e.status, e.stdout, e.stderr,
+	e.stdout_truncated, e.stderr_truncated, e.compile_log,
+	e.execution_time_ms, e.memory_usage_kb, e.cache_hit, e.toolchain,
+	e.backend, e.requested_by, e.created_at, e.updated_at, e.exit_code`
+
+func scanExecution(row rowScanner) (*model.Execution, error) {
+	var e model.Execution
+	err := row.Scan(
+		&e.ID,
+		&e.PasteID,
+		&e.Code,
+		&e.Language,
+		&e.Stdin,
+		&e.TimeLimit,
+		&e.MemoryLimit,
+		&e.Status,
+		&e.Stdout,
+		&e.Stderr,
+		&e.StdoutTruncated,
+		&e.StderrTruncated,
+		&e.CompileLog,
+		&e.ExecutionTimeMs,
+		&e.MemoryUsageKb,
+		&e.CacheHit,
+		&e.Toolchain,
+		&e.BackEnd,
+		&e.RequestedBy,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+		&e.ExitCode)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveExecution stores a new run. An initial run that is already finished,
+// with a result reused by deduplication, queues the paste's callback.
+func (s *PostgresStore) SaveExecution(e *model.Execution) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	// Always update the UpdatedAt timestamp on an update operation
-	p.UpdatedAt = time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin insert of execution %s: %w", e.ID, err)
+	}
+	defer tx.Rollback()
 
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE pastes SET
+	if err := insertExecution(ctx, tx, e); err != nil {
+		return err
+	}
+	if e.IsInitial() && e.Status.IsTerminal() {
+		if err := queueWebhook(ctx, tx, e.PasteID, e.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func insertExecution(ctx context.Context, tx *sql.Tx, e *model.Execution) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO executions (
+			id, paste_id, stdin, time_limit, memory_limit,
+			status, stdout, stderr, stdout_truncated, stderr_truncated,
+			compile_log, execution_time_ms, memory_usage_kb, cache_hit,
+			toolchain, backend, requested_by, created_at, updated_at, exit_code
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+		e.ID, e.PasteID, e.Stdin, e.TimeLimit, e.MemoryLimit,
+		e.Status, e.Stdout, e.Stderr, e.StdoutTruncated, e.StderrTruncated,
+		e.CompileLog, e.ExecutionTimeMs, e.MemoryUsageKb, e.CacheHit,
+		e.Toolchain, e.BackEnd, e.RequestedBy, e.CreatedAt, e.UpdatedAt, e.ExitCode)
+	if err != nil {
+		return fmt.Errorf("failed to insert execution %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetExecution(id string) (*model.Execution, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	e, err := scanExecution(s.db.QueryRowContext(ctx,
+		`SELECT `+executionColumns+`
+		FROM executions e JOIN pastes p ON p.id = e.paste_id
+		WHERE e.id = $1`, id))
+	if err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+// GetExecutions returns all runs of a paste, newest first.
+func (s *PostgresStore) GetExecutions(pasteID string) ([]*model.Execution, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+executionColumns+`
+		FROM executions e JOIN pastes p ON p.id = e.paste_id
+		WHERE e.paste_id = $1
+		ORDER BY e.created_at DESC`, pasteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query executions of paste %s: %w", pasteID, err)
+	}
+	defer rows.Close()
+
+	var executions []*model.Execution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan executions of paste %s: %w", pasteID, err)
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+// UpdateExecution stores the result of a run. The result of the initial run
+// is mirrored onto the paste in the same transaction, and once it is
+// finished the paste's callback is queued. The time of a finished run is
+// charged to the user who requested it.
+func (s *PostgresStore) UpdateExecution(e *model.Execution) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	e.UpdatedAt = time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin update for execution %s: %w", e.ID, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE executions SET
 			status = $1,
 			stdout = $2,
 			stderr = $3,
-			execution_time_ms = $4,
-			memory_usage_kb = $5,
-			updated_at = $6,  
-			backend = $7,
-			compile_log = $8
-		WHERE id = $9; `,
-		p.Status,
-		p.Stdout,
-		p.Stderr,
-		p.ExecutionTimeMs,
-		p.MemoryUsageKb,
-		p.UpdatedAt,
-		p.BackEnd,
-		p.CompileLog,
-		p.ID,
+			stdout_truncated = $4,
+			stderr_truncated = $5,
+			compile_log = $6,
+			execution_time_ms = $7,
+			memory_usage_kb = $8,
+			cache_hit = $9,
+			toolchain = $10,
+			backend = $11,
+			updated_at = $12,
+			exit_code = $13
+		WHERE id = $14`,
+		e.Status,
+		e.Stdout,
+		e.Stderr,
+		e.StdoutTruncated,
+		e.StderrTruncated,
+		e.CompileLog,
+		e.ExecutionTimeMs,
+		e.MemoryUsageKb,
+		e.CacheHit,
+		e.Toolchain,
+		e.BackEnd,
+		e.UpdatedAt,
+		e.ExitCode,
+		e.ID,
 	)
-
 	if err != nil {
-		return fmt.Errorf("failed to execute update for paste with id %s: %w", p.ID, err)
+		return fmt.Errorf("failed to execute update for execution with id %s: %w", e.ID, err)
 	}
 
-	return nil
+	if e.IsInitial() {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE pastes SET
+				status = $1,
+				stdout = $2,
+				stderr = $3,
+				stdout_truncated = $4,
+				stderr_truncated = $5,
+				compile_log = $6,
+				execution_time_ms = $7,
+				memory_usage_kb = $8,
+				cache_hit = $9,
+				toolchain = $10,
+				backend = $11,
+				updated_at = $12,
+				exit_code = $13
+			WHERE id = $14`,
+			e.Status,
+			e.Stdout,
+			e.Stderr,
+			e.StdoutTruncated,
+			e.StderrTruncated,
+			e.CompileLog,
+			e.ExecutionTimeMs,
+			e.MemoryUsageKb,
+			e.CacheHit,
+			e.Toolchain,
+			e.BackEnd,
+			e.UpdatedAt,
+			e.ExitCode,
+			e.PasteID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to execute update for paste with id %s: %w", e.PasteID, err)
+		}
+
+		if e.Status.IsTerminal() {
+			if err := queueWebhook(ctx, tx, e.PasteID, e.UpdatedAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if e.Status.IsTerminal() && e.RequestedBy != "" && e.ExecutionTimeMs > 0 {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO cpu_usage (user_id, day, execution_time_ms) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, day) DO UPDATE SET
+				execution_time_ms = cpu_usage.execution_time_ms + EXCLUDED.execution_time_ms`,
+			e.RequestedBy, e.UpdatedAt.UTC().Format(time.DateOnly), e.ExecutionTimeMs)
+		if err != nil {
+			return fmt.Errorf("failed to charge execution %s: %w", e.ID, err)
+		}
+	}
+
+	return tx.Commit()
 }
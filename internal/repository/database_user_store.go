@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"runbin/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// userColumns lists the columns read by scanUser, in order.
+const userColumns = `id, name, email, oidc_issuer, oidc_subject, created_at`
+
+func scanUser(row rowScanner) (*model.User, error) {
+	var u model.User
+	err := row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.OIDCIssuer,
+		&u.OIDCSubject,
+		&u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *PostgresStore) SaveUser(u *model.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		u.ID, u.Name, u.Email, u.OIDCIssuer, u.OIDCSubject, u.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetUser(id string) (*model.User, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	u, err := scanUser(s.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+	if err != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+func (s *PostgresStore) GetUserByIdentity(issuer, subject string) (*model.User, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	u, err := scanUser(s.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users
+		WHERE oidc_issuer = $1 AND oidc_subject = $2 AND oidc_subject <> ''`, issuer, subject))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Get user by identity error: %v", err)
+		}
+		return nil, false
+	}
+	return u, true
+}
+
+func (s *PostgresStore) CountUsers() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return n, nil
+}
+
+// apiKeyColumns lists the columns read by scanAPIKey, in order.
+const apiKeyColumns = `id, user_id, name, key_hash, scopes, created_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+	var k model.APIKey
+	var scopes []string
+	err := row.Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.KeyHash,
+		pq.Array(&scopes),
+		&k.CreatedAt,
+		&k.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		k.Scopes = append(k.Scopes, model.Scope(scope))
+	}
+	return &k, nil
+}
+
+func (s *PostgresStore) SaveAPIKey(k *model.APIKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	scopes := make([]string, 0, len(k.Scopes))
+	for _, scope := range k.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		k.ID, k.UserID, k.Name, k.KeyHash, pq.Array(scopes), k.CreatedAt, k.RevokedAt)
+	return err
+}
+
+func (s *PostgresStore) GetAPIKeyByHash(hash string) (*model.APIKey, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Get API key error: %v", err)
+		}
+		return nil, false
+	}
+	return k, true
+}
+
+// GetAPIKeys returns the keys of a user, including revoked ones, newest
+// first.
+func (s *PostgresStore) GetAPIKeys(userID string) ([]*model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API keys of user %s: %w", userID, err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey marks a key as revoked. It reports false if the key does not
+// exist or was already revoked.
+func (s *PostgresStore) RevokeAPIKey(id string, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = $1
+		WHERE id = $2 AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key %s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key %s: %w", id, err)
+	}
+	return n == 1, nil
+}
+
+func (s *PostgresStore) SaveSession(session *model.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES ($1, $2, $3, $4)`,
+		session.TokenHash, session.UserID, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+func (s *PostgresStore) GetSession(tokenHash string) (*model.Session, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var session model.Session
+	err := s.db.QueryRowContext(ctx,
+		`SELECT token_hash, user_id, created_at, expires_at FROM sessions WHERE token_hash = $1`, tokenHash).
+		Scan(&session.TokenHash, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, false
+	}
+	return &session, true
+}
+
+func (s *PostgresStore) DeleteSession(tokenHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = $1`, tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteExpiredSessions(now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runbin/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queueWebhook queues the callback of a paste, if it has one. The unique
+// paste_id keeps a result stored twice from notifying twice.
+func queueWebhook(ctx context.Context, tx *sql.Tx, pasteID string, now time.Time) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (id, paste_id, url, next_attempt_at, created_at)
+		SELECT $1, id, callback_url, $2, $2 FROM pastes
+		WHERE id = $3 AND callback_url <> ''
+		ON CONFLICT (paste_id) DO NOTHING`,
+		uuid.NewString(), now, pasteID)
+	if err != nil {
+		return fmt.Errorf("failed to queue callback of paste %s: %w", pasteID, err)
+	}
+	return nil
+}
+
+const webhookColumns = `
+	id, paste_id, url, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func scanWebhookDelivery(row rowScanner) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	err := row.Scan(
+		&d.ID,
+		&d.PasteID,
+		&d.URL,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.CreatedAt,
+		&d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ClaimWebhookDeliveries takes up to limit pending deliveries that are due
+// and leases them by moving their next attempt to leaseUntil. Rows locked
+// by another API process are skipped.
+func (s *PostgresStore) ClaimWebhookDeliveries(now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT $4
+		)
+		RETURNING `+webhookColumns,
+		leaseUntil, model.WebhookPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim callbacks: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan callback: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt.
+func (s *PostgresStore) UpdateWebhookDelivery(d *model.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET
+			status = $1,
+			attempts = $2,
+			next_attempt_at = $3,
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = $6
+		WHERE id = $7`,
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.DeliveredAt, d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update callback %s: %w", d.ID, err)
+	}
+	return nil
+}
@@ -3,13 +3,76 @@ package repository
 import (
 	"context"
 	"runbin/internal/model"
+	"time"
 )
 
 type PasteRepository interface {
 	Save(p *model.Paste) error
-	Update(p *model.Paste) error
 	GetByID(id string) (*model.Paste, bool)
 	DispatchExecutionTask(id string) error
-	GetTask(ctx context.Context) (*model.Paste, error)
+	GetTask(ctx context.Context) (*model.Execution, error)
+	FindReusableResult(hash string, since time.Time) (*model.Paste, bool)
+	GetHistory(id string) ([]*model.Paste, error)
+	SaveExecution(e *model.Execution) error
+	UpdateExecution(e *model.Execution) error
+	GetExecution(id string) (*model.Execution, bool)
+	GetExecutions(pasteID string) ([]*model.Execution, error)
+	UpdateSettings(p *model.Paste) error
+	Delete(id string) error
+	Burn(id string) (bool, error)
+	DeleteExpired(now time.Time) (int64, error)
+	GetByOwner(ownerID string) ([]*model.Paste, error)
+	ListPastes(filter model.PasteFilter) ([]*model.Paste, error)
+	GetCPUUsage(userID string, day time.Time) (int64, error)
+	// SaveBatch stores a batch with its pastes and their initial runs and
+	// queues the runs, atomically.
+	SaveBatch(b *model.Batch, pastes []*model.Paste, runs []*model.Execution) error
+	GetBatch(id string) (*model.Batch, bool)
+	GetBatchPastes(batchID string) ([]*model.Paste, error)
 }
 
+type UserRepository interface {
+	SaveUser(u *model.User) error
+	GetUser(id string) (*model.User, bool)
+	CountUsers() (int, error)
+	SaveAPIKey(k *model.APIKey) error
+	GetAPIKeyByHash(hash string) (*model.APIKey, bool)
+	GetAPIKeys(userID string) ([]*model.APIKey, error)
+	RevokeAPIKey(id string, at time.Time) (bool, error)
+	GetUserByIdentity(issuer, subject string) (*model.User, bool)
+	SaveSession(s *model.Session) error
+	GetSession(tokenHash string) (*model.Session, bool)
+	DeleteSession(tokenHash string) error
+	DeleteExpiredSessions(now time.Time) (int64, error)
+}
+
+// RateLimitStore keeps token buckets. Sharing one store between API
+// replicas makes them enforce a common limit.
+type RateLimitStore interface {
+	// Take removes n tokens from the bucket named key, which refills at rate
+	// tokens per second up to burst. If the bucket holds fewer than n it
+	// returns how long until it does and takes nothing.
+	Take(key string, n int, rate float64, burst int, now time.Time) (time.Duration, error)
+	// DeleteIdleBuckets forgets buckets last used before the given time.
+	DeleteIdleBuckets(before time.Time) (int64, error)
+}
+
+// ChangeFeed reports changes to pastes, including those made by other
+// processes such as the worker.
+type ChangeFeed interface {
+	// WatchPastes calls changed with the ID of each paste whose status
+	// changes, until ctx is done. An empty ID means that notifications may
+	// have been lost.
+	WatchPastes(ctx context.Context, changed func(id string)) error
+}
+
+// WebhookStore keeps the callbacks queued when pastes are finished, and the
+// outcome of their delivery.
+type WebhookStore interface {
+	// ClaimWebhookDeliveries returns up to limit pending deliveries that
+	// are due at now, and postpones them to leaseUntil so that no one else
+	// attempts them in the meantime.
+	ClaimWebhookDeliveries(now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error)
+	// UpdateWebhookDelivery records the outcome of an attempt.
+	UpdateWebhookDelivery(d *model.WebhookDelivery) error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"runbin/internal/model"
+	"slices"
+)
+
+func (s *MemoryPasteStore) SaveBatch(b *model.Batch, pastes []*model.Paste, runs []*model.Execution) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := make([]string, len(pastes))
+	for i, p := range pastes {
+		s.pastes[p.ID] = p
+		ids[i] = p.ID
+	}
+	for _, e := range runs {
+		s.executions[e.ID] = e
+		if e.Status.IsTerminal() {
+			s.queueWebhookLocked(e.PasteID, e.UpdatedAt)
+		}
+	}
+	s.batches[b.ID] = b
+	s.batchItems[b.ID] = ids
+	return nil
+}
+
+func (s *MemoryPasteStore) GetBatch(id string) (*model.Batch, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	b, found := s.batches[id]
+	return b, found
+}
+
+// GetBatchPastes returns the pastes of a batch in the order they were
+// submitted. Pastes deleted since are left out.
+func (s *MemoryPasteStore) GetBatchPastes(batchID string) ([]*model.Paste, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var pastes []*model.Paste
+	for _, id := range s.batchItems[batchID] {
+		if p, ok := s.pastes[id]; ok {
+			pastes = append(pastes, p)
+		}
+	}
+	return pastes, nil
+}
+
+// deleteEmptyBatchesLocked forgets the batches whose pastes have all been
+// deleted. The caller must hold s.mutex.
+func (s *MemoryPasteStore) deleteEmptyBatchesLocked() {
+	for id, items := range s.batchItems {
+		remaining := slices.ContainsFunc(items, func(pasteID string) bool {
+			_, ok := s.pastes[pasteID]
+			return ok
+		})
+		if !remaining {
+			delete(s.batchItems, id)
+			delete(s.batches, id)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryRateLimitStore keeps token buckets in process. Each API replica
+// then enforces its own limits.
+type MemoryRateLimitStore struct {
+	buckets map[string]*bucket
+	mutex   sync.Mutex
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, n int, rate float64, burst int, now time.Time) (time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	tokens, wait := refill(b.tokens, b.updatedAt, n, rate, burst, now)
+	b.tokens, b.updatedAt = tokens, now
+	return wait, nil
+}
+
+func (s *MemoryRateLimitStore) DeleteIdleBuckets(before time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var n int64
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(before) {
+			delete(s.buckets, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// refill tops up a bucket holding tokens since updatedAt and takes n tokens
+// from it. It returns the new token count and, if the bucket held fewer
+// than n, how long until it does.
+func refill(tokens float64, updatedAt time.Time, n int, rate float64, burst int, now time.Time) (float64, time.Duration) {
+	tokens = min(float64(burst), tokens+now.Sub(updatedAt).Seconds()*rate)
+	if tokens >= float64(n) {
+		return tokens - float64(n), 0
+	}
+	return tokens, time.Duration((float64(n) - tokens) / rate * float64(time.Second))
+}
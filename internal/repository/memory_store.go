@@ -3,18 +3,42 @@ package repository
 import (
 	"context"
 	"runbin/internal/model"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
 type MemoryPasteStore struct {
-	pastes map[string]*model.Paste
-	mutex  sync.RWMutex
+	// Stored pastes are replaced rather than changed in place, as GetByID
+	// hands them out to readers that do not hold the lock
+	pastes     map[string]*model.Paste
+	executions map[string]*model.Execution
+	users      map[string]*model.User
+	apiKeys    map[string]*model.APIKey
+	sessions   map[string]*model.Session
+	cpuUsage   map[string]int64
+	// Callbacks by paste ID
+	webhooks map[string]*model.WebhookDelivery
+	batches  map[string]*model.Batch
+	// Paste IDs of each batch, in the order they were submitted
+	batchItems map[string][]string
+	mutex      sync.RWMutex
+	// Called with the ID of a paste whose status changed, see WatchPastes
+	changed func(id string)
 }
 
 func NewMemoryPasteStore() *MemoryPasteStore {
 	return &MemoryPasteStore{
-		pastes: make(map[string]*model.Paste),
+		pastes:     make(map[string]*model.Paste),
+		executions: make(map[string]*model.Execution),
+		users:      make(map[string]*model.User),
+		apiKeys:    make(map[string]*model.APIKey),
+		sessions:   make(map[string]*model.Session),
+		cpuUsage:   make(map[string]int64),
+		webhooks:   make(map[string]*model.WebhookDelivery),
+		batches:    make(map[string]*model.Batch),
+		batchItems: make(map[string][]string),
 	}
 }
 
@@ -37,14 +61,278 @@ func (s *MemoryPasteStore) DispatchExecutionTask(id string) error {
 	return nil // 内存存储暂不实现队列功能
 }
 
-func (s *MemoryPasteStore) GetTask(ctx context.Context) (*model.Paste, error) {
+func (s *MemoryPasteStore) GetTask(ctx context.Context) (*model.Execution, error) {
 
 	return nil, nil
 }
 
-func (s *MemoryPasteStore) Update(p *model.Paste) error {
+func (s *MemoryPasteStore) FindReusableResult(hash string, since time.Time) (*model.Paste, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// The toolchain most recently used for each language stands in for the
+	// one the next execution would run on
+	latest := make(map[string]*model.Paste)
+	for _, p := range s.pastes {
+		if p.Toolchain == "" {
+			continue
+		}
+		if cur, ok := latest[p.Language]; !ok || p.UpdatedAt.After(cur.UpdatedAt) {
+			latest[p.Language] = p
+		}
+	}
+
+	// The ID of the source is revealed to whoever submits the same code
+	now := time.Now()
+	var found *model.Paste
+	for _, p := range s.pastes {
+		if p.ExecutionHash != hash || p.CachedFrom != "" || p.UpdatedAt.Before(since) {
+			continue
+		}
+		if p.Visibility != model.VisibilityPublic || p.BurnAfterReading || p.IsExpired(now) {
+			continue
+		}
+		if !slices.Contains(model.DeterministicStatuses, p.Status) {
+			continue
+		}
+		if cur, ok := latest[p.Language]; !ok || cur.Toolchain != p.Toolchain {
+			continue
+		}
+		if found == nil || p.UpdatedAt.After(found.UpdatedAt) {
+			found = p
+		}
+	}
+	return found, found != nil
+}
+
+func (s *MemoryPasteStore) GetHistory(id string) ([]*model.Paste, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var history []*model.Paste
+	for p, ok := s.pastes[id]; ok; p, ok = s.pastes[p.ParentID] {
+		history = append(history, p)
+	}
+	slices.Reverse(history)
+	return history, nil
+}
+
+func (s *MemoryPasteStore) SaveExecution(e *model.Execution) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.pastes[p.ID] = p
+	s.executions[e.ID] = e
+	if e.IsInitial() && e.Status.IsTerminal() {
+		s.queueWebhookLocked(e.PasteID, e.UpdatedAt)
+	}
+	return nil
+}
+
+func (s *MemoryPasteStore) UpdateExecution(e *model.Execution) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e.UpdatedAt = time.Now()
+	s.executions[e.ID] = e
+	if stored, ok := s.pastes[e.PasteID]; ok && e.IsInitial() {
+		p := *stored
+		e.ApplyTo(&p)
+		p.UpdatedAt = e.UpdatedAt
+		s.pastes[p.ID] = &p
+		s.notifyLocked(p.ID)
+		if e.Status.IsTerminal() {
+			s.queueWebhookLocked(p.ID, e.UpdatedAt)
+		}
+	}
+	if e.Status.IsTerminal() && e.RequestedBy != "" {
+		s.cpuUsage[cpuUsageKey(e.RequestedBy, e.UpdatedAt)] += int64(e.ExecutionTimeMs)
+	}
 	return nil
 }
+
+func (s *MemoryPasteStore) GetExecution(id string) (*model.Execution, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	e, found := s.executions[id]
+	return e, found
+}
+
+func (s *MemoryPasteStore) GetExecutions(pasteID string) ([]*model.Execution, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var executions []*model.Execution
+	for _, e := range s.executions {
+		if e.PasteID == pasteID {
+			executions = append(executions, e)
+		}
+	}
+	slices.SortFunc(executions, func(a, b *model.Execution) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return executions, nil
+}
+
+func (s *MemoryPasteStore) UpdateSettings(p *model.Paste) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if stored, ok := s.pastes[p.ID]; ok {
+		updated := *stored
+		updated.ExpiresAt = p.ExpiresAt
+		updated.Visibility = p.Visibility
+		updated.PasswordHash = p.PasswordHash
+		s.pastes[p.ID] = &updated
+	}
+	return nil
+}
+
+func (s *MemoryPasteStore) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.deleteLocked(id)
+	return nil
+}
+
+// deleteLocked removes a paste with its executions and callback. The caller
+// must hold s.mutex.
+func (s *MemoryPasteStore) deleteLocked(id string) {
+	delete(s.webhooks, id)
+	for eid, e := range s.executions {
+		if e.PasteID == id {
+			delete(s.executions, eid)
+		}
+	}
+	delete(s.pastes, id)
+}
+
+func (s *MemoryPasteStore) Burn(id string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	stored, ok := s.pastes[id]
+	if !ok || !stored.BurnAfterReading || stored.IsExpired(now) {
+		return false, nil
+	}
+	burned := *stored
+	burned.ExpiresAt = &now
+	s.pastes[id] = &burned
+	return true, nil
+}
+
+func (s *MemoryPasteStore) DeleteExpired(now time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var n int64
+	for id, p := range s.pastes {
+		if !p.IsExpired(now) {
+			continue
+		}
+		s.deleteLocked(id)
+		n++
+	}
+	s.deleteEmptyBatchesLocked()
+	return n, nil
+}
+
+// GetByOwner returns the pastes of a user, newest first.
+func (s *MemoryPasteStore) GetByOwner(ownerID string) ([]*model.Paste, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var pastes []*model.Paste
+	for _, p := range s.pastes {
+		if p.OwnerID == ownerID {
+			pastes = append(pastes, p)
+		}
+	}
+	slices.SortFunc(pastes, func(a, b *model.Paste) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return pastes, nil
+}
+
+func (s *MemoryPasteStore) GetCPUUsage(userID string, day time.Time) (int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cpuUsage[cpuUsageKey(userID, day)], nil
+}
+
+func cpuUsageKey(userID string, day time.Time) string {
+	return userID + "/" + day.UTC().Format(time.DateOnly)
+}
+
+// ListPastes returns up to filter.Limit pastes matching filter, newest
+// first. The full-text query is approximated by requiring every word of it
+// to occur in the code, ignoring case.
+func (s *MemoryPasteStore) ListPastes(filter model.PasteFilter) ([]*model.Paste, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := time.Now()
+	terms := strings.Fields(strings.ToLower(filter.Query))
+
+	var pastes []*model.Paste
+	for _, p := range s.pastes {
+		if p.IsExpired(now) || p.BurnAfterReading {
+			continue
+		}
+		if p.Visibility != model.VisibilityPublic && (filter.ViewerID == "" || p.OwnerID != filter.ViewerID) {
+			continue
+		}
+		if (filter.Language != "" && p.Language != filter.Language) ||
+			(filter.Status != "" && p.Status != filter.Status) ||
+			(filter.OwnerID != "" && p.OwnerID != filter.OwnerID) ||
+			(filter.BackEnd != "" && p.BackEnd != filter.BackEnd) {
+			continue
+		}
+		if (filter.CreatedAfter != nil && p.CreatedAt.Before(*filter.CreatedAfter)) ||
+			(filter.CreatedBefore != nil && !p.CreatedAt.Before(*filter.CreatedBefore)) {
+			continue
+		}
+		if filter.After != nil && comparePastes(p, filter.After) <= 0 {
+			continue
+		}
+		code := strings.ToLower(p.Code)
+		if !slices.ContainsFunc(terms, func(t string) bool { return !strings.Contains(code, t) }) {
+			pastes = append(pastes, p)
+		}
+	}
+
+	slices.SortFunc(pastes, func(a, b *model.Paste) int {
+		return comparePastes(a, &model.PasteCursor{CreatedAt: b.CreatedAt, ID: b.ID})
+	})
+	if len(pastes) > filter.Limit {
+		pastes = pastes[:filter.Limit]
+	}
+	return pastes, nil
+}
+
+// comparePastes orders p relative to cursor in listing order, newest first.
+func comparePastes(p *model.Paste, cursor *model.PasteCursor) int {
+	if c := cursor.CreatedAt.Compare(p.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(cursor.ID, p.ID)
+}
+
+// WatchPastes reports the changes made through this store, which is only
+// used within one process.
+func (s *MemoryPasteStore) WatchPastes(ctx context.Context, changed func(id string)) error {
+	s.mutex.Lock()
+	s.changed = changed
+	s.mutex.Unlock()
+
+	<-ctx.Done()
+
+	s.mutex.Lock()
+	s.changed = nil
+	s.mutex.Unlock()
+	return nil
+}
+
+// notifyLocked reports a change to a paste. The caller must hold s.mutex.
+func (s *MemoryPasteStore) notifyLocked(id string) {
+	if s.changed != nil {
+		s.changed(id)
+	}
+}
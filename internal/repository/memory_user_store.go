@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"runbin/internal/model"
+	"slices"
+	"time"
+)
+
+func (s *MemoryPasteStore) SaveUser(u *model.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.users[u.ID] = u
+	return nil
+}
+
+func (s *MemoryPasteStore) GetUser(id string) (*model.User, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	u, found := s.users[id]
+	return u, found
+}
+
+func (s *MemoryPasteStore) CountUsers() (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.users), nil
+}
+
+func (s *MemoryPasteStore) SaveAPIKey(k *model.APIKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.apiKeys[k.ID] = k
+	return nil
+}
+
+func (s *MemoryPasteStore) GetAPIKeyByHash(hash string) (*model.APIKey, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, k := range s.apiKeys {
+		if k.KeyHash == hash {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// GetAPIKeys returns the keys of a user, including revoked ones, newest
+// first.
+func (s *MemoryPasteStore) GetAPIKeys(userID string) ([]*model.APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var keys []*model.APIKey
+	for _, k := range s.apiKeys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b *model.APIKey) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return keys, nil
+}
+
+func (s *MemoryPasteStore) RevokeAPIKey(id string, at time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	k, ok := s.apiKeys[id]
+	if !ok || k.IsRevoked() {
+		return false, nil
+	}
+	k.RevokedAt = &at
+	return true, nil
+}
+
+func (s *MemoryPasteStore) GetUserByIdentity(issuer, subject string) (*model.User, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, u := range s.users {
+		if u.OIDCSubject != "" && u.OIDCIssuer == issuer && u.OIDCSubject == subject {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+func (s *MemoryPasteStore) SaveSession(session *model.Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions[session.TokenHash] = session
+	return nil
+}
+
+func (s *MemoryPasteStore) GetSession(tokenHash string) (*model.Session, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	session, found := s.sessions[tokenHash]
+	return session, found
+}
+
+func (s *MemoryPasteStore) DeleteSession(tokenHash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, tokenHash)
+	return nil
+}
+
+func (s *MemoryPasteStore) DeleteExpiredSessions(now time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var n int64
+	for hash, session := range s.sessions {
+		if session.IsExpired(now) {
+			delete(s.sessions, hash)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"runbin/internal/model"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queueWebhookLocked queues the callback of a paste, if it has one and it
+// was not queued before. The caller must hold s.mutex.
+func (s *MemoryPasteStore) queueWebhookLocked(pasteID string, now time.Time) {
+	p, ok := s.pastes[pasteID]
+	if !ok || p.CallbackURL == "" || s.webhooks[pasteID] != nil {
+		return
+	}
+	s.webhooks[pasteID] = &model.WebhookDelivery{
+		ID:            uuid.NewString(),
+		PasteID:       pasteID,
+		URL:           p.CallbackURL,
+		Status:        model.WebhookPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+func (s *MemoryPasteStore) ClaimWebhookDeliveries(now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due []*model.WebhookDelivery
+	for _, d := range s.webhooks {
+		if d.Status == model.WebhookPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(a, b *model.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	claimed := make([]*model.WebhookDelivery, 0, min(limit, len(due)))
+	for _, d := range due[:min(limit, len(due))] {
+		d.NextAttemptAt = leaseUntil
+		claim := *d
+		claimed = append(claimed, &claim)
+	}
+	return claimed, nil
+}
+
+func (s *MemoryPasteStore) UpdateWebhookDelivery(d *model.WebhookDelivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.webhooks[d.PasteID]; ok {
+		stored := *d
+		s.webhooks[d.PasteID] = &stored
+	}
+	return nil
+}
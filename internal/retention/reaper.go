@@ -0,0 +1,78 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"runbin/internal/config"
+	"runbin/internal/repository"
+)
+
+// Reaper periodically deletes expired pastes and login sessions, and rate
+// limit buckets that have been idle long enough to be full again.
+type Reaper struct {
+	repo     repository.PasteRepository
+	users    repository.UserRepository
+	limits   repository.RateLimitStore
+	interval time.Duration
+	idle     time.Duration
+}
+
+// NewReaper returns a reaper. limits may be nil when rate limiting is
+// disabled.
+func NewReaper(repo repository.PasteRepository, users repository.UserRepository, limits repository.RateLimitStore, cfg *config.ApiConfig) *Reaper {
+	return &Reaper{
+		repo:     repo,
+		users:    users,
+		limits:   limits,
+		interval: time.Duration(cfg.Retention.Interval) * time.Second,
+		idle:     max(refillTime(cfg.RateLimit.IP), refillTime(cfg.RateLimit.Key)),
+	}
+}
+
+// refillTime is how long an empty bucket takes to fill up.
+func refillTime(bucket config.BucketConfig) time.Duration {
+	if bucket.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(bucket.Burst) / bucket.Rate * float64(time.Second))
+}
+
+// Run sweeps every interval until ctx is done. LoadApi ensures the interval
+// is positive.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.sweep(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reaper) sweep(now time.Time) {
+	n, err := r.repo.DeleteExpired(now)
+	if err != nil {
+		log.Printf("Retention cleanup error: %v", err)
+	} else if n > 0 {
+		log.Printf("Retention cleanup removed %d expired pastes", n)
+	}
+
+	n, err = r.users.DeleteExpiredSessions(now)
+	if err != nil {
+		log.Printf("Session cleanup error: %v", err)
+	} else if n > 0 {
+		log.Printf("Session cleanup removed %d expired sessions", n)
+	}
+
+	if r.limits != nil {
+		if _, err := r.limits.DeleteIdleBuckets(now.Add(-r.idle)); err != nil {
+			log.Printf("Rate limit cleanup error: %v", err)
+		}
+	}
+}
@@ -1,16 +1,94 @@
 package router
 
 import (
+	"net/http"
+
+	"runbin/internal/apierror"
 	"runbin/internal/controller"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+	"runbin/internal/openapi"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(engine *gin.Engine, handler *controller.PasteHandler) {
+// Handlers bundles what SetupRoutes wires up. OIDC is nil when SSO is
+// disabled and RateLimit when rate limiting is.
+type Handlers struct {
+	Paste        *controller.PasteHandler
+	User         *controller.UserHandler
+	OIDC         *controller.OIDCHandler
+	Authenticate gin.HandlerFunc
+	RateLimit    gin.HandlerFunc
+	MaxBodySize  gin.HandlerFunc
+}
+
+func SetupRoutes(engine *gin.Engine, h Handlers) {
+	read := []gin.HandlerFunc{middleware.OptionalScope(model.ScopeRead)}
+	manage := []gin.HandlerFunc{middleware.OptionalScope(model.ScopeSubmit)}
+	// Routes that queue executions are also rate limited
+	submit := []gin.HandlerFunc{middleware.OptionalScope(model.ScopeSubmit)}
+	if h.RateLimit != nil {
+		submit = append(submit, h.RateLimit)
+	}
+
 	api := engine.Group("/api")
+	api.Use(h.MaxBodySize, h.Authenticate)
 	{
-		api.POST("/pastes", handler.SubmitPaste)
-		api.GET("/pastes/:id", handler.GetPaste)
-		api.GET("/languages", handler.GetLanguages)
+		// The unversioned routes answer as they did before /api/v1 and are
+		// kept for existing clients
+		v1 := api.Group("/v1", middleware.APIVersion(1))
+		legacy := api.Group("", middleware.Deprecated("/api", "/api/v1"))
+		for _, g := range []*gin.RouterGroup{v1, legacy} {
+			versionedRoutes(g, h, read, manage, submit)
+		}
+
+		api.GET("/openapi.json", openapi.Handler)
+
+		if h.OIDC != nil {
+			api.GET("/auth/login", h.OIDC.Login)
+			api.GET("/auth/callback", h.OIDC.Callback)
+			api.POST("/auth/logout", h.OIDC.Logout)
+		}
 	}
+
+	// Pastebin-style uploads from a terminal
+	root := engine.Group("/", h.MaxBodySize, h.Authenticate)
+	root.POST("/", with(submit, h.Paste.Upload)...)
+
+	engine.NoRoute(func(c *gin.Context) {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, "Not found")
+	})
+}
+
+// versionedRoutes registers the routes that exist in every API version.
+func versionedRoutes(api *gin.RouterGroup, h Handlers, read, manage, submit []gin.HandlerFunc) {
+	api.GET("/pastes", with(read, h.Paste.ListPastes)...)
+	api.POST("/pastes", with(submit, h.Paste.SubmitPaste)...)
+	api.GET("/pastes/:id", with(read, h.Paste.GetPaste)...)
+	api.PATCH("/pastes/:id", with(manage, h.Paste.PatchPaste)...)
+	api.DELETE("/pastes/:id", with(manage, h.Paste.DeletePaste)...)
+	api.POST("/pastes/:id/fork", with(submit, h.Paste.ForkPaste)...)
+	api.GET("/pastes/:id/history", with(read, h.Paste.GetHistory)...)
+	api.GET("/pastes/:id/raw", with(read, h.Paste.GetRawCode)...)
+	api.GET("/pastes/:id/raw/:stream", with(read, h.Paste.GetRawOutput)...)
+	api.GET("/pastes/:id/archive", with(read, h.Paste.GetArchive)...)
+	api.POST("/pastes/:id/runs", with(submit, h.Paste.RunPaste)...)
+	api.GET("/pastes/:id/runs", with(read, h.Paste.GetRuns)...)
+	api.GET("/pastes/:id/runs/:run_id", with(read, h.Paste.GetRun)...)
+	api.POST("/batches", with(submit, h.Paste.CreateBatch)...)
+	api.GET("/batches/:id", with(read, h.Paste.GetBatch)...)
+	api.GET("/languages", h.Paste.GetLanguages)
+
+	api.POST("/users", middleware.RequireScope(model.ScopeAdmin), h.User.CreateUser)
+	api.GET("/users/me", middleware.RequireAuth(), h.User.GetMe)
+	api.GET("/users/me/pastes", middleware.RequireScope(model.ScopeRead), h.User.GetMyPastes)
+	api.GET("/keys", middleware.RequireAuth(), h.User.GetKeys)
+	api.POST("/keys", middleware.RequireAuth(), h.User.CreateKey)
+	api.DELETE("/keys/:id", middleware.RequireAuth(), h.User.RevokeKey)
+}
+
+// with appends handler to a chain of middleware.
+func with(chain []gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	return append(chain[:len(chain):len(chain)], handler)
 }
@@ -0,0 +1,35 @@
+package router
+
+import (
+	"testing"
+
+	"runbin/internal/config"
+	"runbin/internal/controller"
+	"runbin/internal/middleware"
+	"runbin/internal/notify"
+	"runbin/internal/openapi"
+	"runbin/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.ApiConfig{}
+	store := repository.NewMemoryPasteStore()
+
+	engine := gin.New()
+	SetupRoutes(engine, Handlers{
+		Paste: controller.NewPasteHandler(store, notify.NewHub(), cfg),
+		User:  controller.NewUserHandler(store, store),
+		// Only registered, never called, so it needs no provider
+		OIDC:         &controller.OIDCHandler{},
+		Authenticate: middleware.Authenticate(store),
+		RateLimit:    middleware.RateLimit(repository.NewMemoryRateLimitStore(), &cfg.RateLimit),
+		MaxBodySize:  middleware.MaxBodySize(1 << 20),
+	})
+
+	for _, route := range openapi.Undocumented(engine.Routes()) {
+		t.Errorf("%s is missing from the OpenAPI document", route)
+	}
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"runbin/internal/config"
+	"runbin/internal/dto"
+	"runbin/internal/model"
+	"runbin/internal/repository"
+)
+
+const (
+	// How often due deliveries are looked for
+	pollInterval = 2 * time.Second
+	// Deliveries attempted at once by each API process
+	batchSize = 10
+
+	initialBackoff = 10 * time.Second
+	maxBackoff     = time.Hour
+)
+
+// Dispatcher attempts the queued callbacks. Several API processes may run
+// one each; a claimed delivery is leased to one of them.
+type Dispatcher struct {
+	repo        repository.PasteRepository
+	store       repository.WebhookStore
+	cfg         *config.WebhookConfig
+	client      *http.Client
+	maxAttempts int
+	timeout     time.Duration
+}
+
+func NewDispatcher(repo repository.PasteRepository, store repository.WebhookStore, cfg *config.WebhookConfig) *Dispatcher {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	return &Dispatcher{
+		repo:  repo,
+		store: store,
+		cfg:   cfg,
+		client: &http.Client{
+			Timeout: timeout,
+			// A redirect could lead anywhere, past the allowlist
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts: max(cfg.MaxAttempts, 1),
+		timeout:     timeout,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.dispatchDue(ctx, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchDue(ctx context.Context, now time.Time) {
+	// The lease outlasts every attempt of the batch, after which a
+	// delivery left behind by a crashed process is due again
+	deliveries, err := d.store.ClaimWebhookDeliveries(now, now.Add(2*d.timeout+time.Minute), batchSize)
+	if err != nil {
+		log.Printf("Callback claim error: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// errPermanent marks failures that another attempt cannot fix.
+var errPermanent = errors.New("permanent failure")
+
+// attempt sends a delivery once and records the outcome. Failed attempts
+// are retried with exponential backoff until maxAttempts is reached.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := d.send(ctx, delivery)
+	delivery.LastStatusCode = status
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = model.WebhookDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case errors.Is(err, errPermanent) || delivery.Attempts >= d.maxAttempts:
+		delivery.Status = model.WebhookFailed
+		delivery.LastError = err.Error()
+		log.Printf("Callback %s of paste %s failed after %d attempts: %v", delivery.ID, delivery.PasteID, delivery.Attempts, err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(backoff(delivery.Attempts))
+	}
+
+	if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+		log.Printf("Callback update error: %v", err)
+	}
+}
+
+// backoff is the delay after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// send posts the paste's result to the callback URL and returns the status
+// of the response, or 0 if there was none.
+func (d *Dispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	// The allowlist may have changed since the paste was submitted
+	u, err := url.Parse(delivery.URL)
+	if err != nil || !HostAllowed(u.Hostname(), d.cfg.AllowedHosts) {
+		return 0, fmt.Errorf("%w: callback host is not allowed", errPermanent)
+	}
+	paste, ok := d.repo.GetByID(delivery.PasteID)
+	if !ok {
+		return 0, fmt.Errorf("%w: paste no longer exists", errPermanent)
+	}
+
+	body, err := json.Marshal(dto.WebhookEvent{
+		Event:      dto.EventPasteFinished,
+		DeliveryID: delivery.ID,
+		Paste:      dto.NewPaste(paste),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RunBin-Webhook")
+	req.Header.Set(headerDelivery, delivery.ID)
+	req.Header.Set(headerEvent, dto.EventPasteFinished)
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, Sign([]byte(d.cfg.Secret), timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,47 @@
+// Package webhook delivers the callbacks of finished pastes. Deliveries are
+// queued by the repository in the transaction that stores the result, so
+// none is lost, and attempted by the API server with exponential backoff.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Headers of a callback request
+const (
+	headerDelivery  = "X-RunBin-Delivery"
+	headerEvent     = "X-RunBin-Event"
+	headerTimestamp = "X-RunBin-Timestamp"
+	headerSignature = "X-RunBin-Signature"
+)
+
+// Sign returns the signature of a callback body sent at timestamp (Unix
+// seconds): the hex HMAC-SHA256, keyed with the secret, of the timestamp, a
+// dot and the body. Signing the timestamp lets receivers reject replays.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// HostAllowed reports whether callbacks may be sent to host. An entry of
+// allowed is either a host name or a wildcard such as *.example.com, which
+// matches its subdomains.
+func HostAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range allowed {
+		entry = strings.ToLower(entry)
+		if host == entry {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"runbin/internal/config"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 type Usage struct {
@@ -21,30 +24,13 @@ type Usage struct {
 	RealTime   float64 `json:"real_time"`
 }
 
-func compileCpp(ctx context.Context, task *model.Paste, cli *client.Client, tmpDir string, cfg *config.WorkerConfig) error {
-	hostConfig := &container.HostConfig{
-		Binds: []string{tmpDir + ":/app"},
-		Resources: container.Resources{
-			Memory:   int64(cfg.Limit.Memory * 1024 * 1024),
-			CPUQuota: int64(cfg.Limit.Cpu * 100000),
-		},
-		NetworkMode: "none",
-	}
-
-	// 写入main.cpp
-	codePath := filepath.Join(tmpDir, "main.cpp")
-	if err := os.WriteFile(codePath, []byte(task.Code), 0644); err != nil {
-		return fmt.Errorf("write code file error: %v", err)
-	}
-
+func compileCpp(ctx context.Context, task *model.Execution, cli *client.Client, tmpDir string, cfg *config.WorkerConfig, hostConfig *container.HostConfig, flags []string) error {
 	compliteCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Limit.Time)*time.Second)
 	defer cancel()
 
 	// 创建容器
-	resp, err := cli.ContainerCreate(compliteCtx, &container.Config{
-		Image: cfg.CompilerImage,
-		Cmd:   []string{"sh", "-c", "g++ -std=c++20 /app/main.cpp -o /app/output > /app/compile.txt 2>&1"},
-	}, hostConfig, nil, nil, filepath.Base(tmpDir)+"_builder")
+	containerConfig := sandboxConfig(cfg, "g++ "+strings.Join(flags, " ")+" /app/main.cpp -o /app/output > /app/compile.txt 2>&1")
+	resp, err := cli.ContainerCreate(compliteCtx, containerConfig, hostConfig, nil, nil, filepath.Base(tmpDir)+"_builder")
 	if err != nil {
 		return fmt.Errorf("create compile container error: %v", err)
 	}
@@ -69,7 +55,9 @@ func compileCpp(ctx context.Context, task *model.Paste, cli *client.Client, tmpD
 		}
 
 		// Non-zero exit code indicates compilation failure
-		if status.StatusCode != 0 {
+		if status.StatusCode == exitCodeFileSizeExceeded {
+			task.Status = model.StatusResourceLimitExceed
+		} else if status.StatusCode != 0 {
 			task.Status = model.StatusCompileError
 		}
 		return nil
@@ -82,30 +70,31 @@ func compileCpp(ctx context.Context, task *model.Paste, cli *client.Client, tmpD
 	}
 }
 
-func runCpp(ctx context.Context, task *model.Paste, cli *client.Client, tmpDir string, cfg *config.WorkerConfig) error {
-	hostConfig := &container.HostConfig{
-		Binds: []string{tmpDir + ":/app"},
-		Resources: container.Resources{
-			Memory:   int64(cfg.Limit.Memory * 1024 * 1024),
-			CPUQuota: int64(cfg.Limit.Cpu * 100000),
-		},
-		NetworkMode: "none",
-	}
-
+func runCpp(ctx context.Context, task *model.Execution, cli *client.Client, tmpDir string, cfg *config.WorkerConfig, hostConfig *container.HostConfig) error {
 	// 写入 input.txt
 	inputPath := filepath.Join(tmpDir, "input.txt")
 	if err := os.WriteFile(inputPath, []byte(task.Stdin), 0644); err != nil {
 		return fmt.Errorf("write input file error: %v", err)
 	}
 
+	// usage.json is bound writable into the otherwise read-only /app, so it
+	// has to exist and be writable by the unprivileged runner user
+	usagePath := filepath.Join(tmpDir, "usage.json")
+	if err := os.WriteFile(usagePath, nil, 0666); err != nil {
+		return fmt.Errorf("create usage file error: %v", err)
+	}
+	if err := os.Chmod(usagePath, 0666); err != nil {
+		return fmt.Errorf("chmod usage file error: %v", err)
+	}
+
 	runCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Limit.Time)*time.Second)
 	defer cancel()
 
 	// Create runner container configuration
-	resp, err := cli.ContainerCreate(runCtx, &container.Config{
-		Image: cfg.CompilerImage,
-		Cmd:   []string{"sh", "-c", `/usr/bin/time --format='{"exit_status":%x,"max_memory":%M,"real_time":%e}' -o /app/usage.json /app/output < /app/input.txt > /app/stdout.txt 2> /app/stderr.txt`},
-	}, hostConfig, nil, nil, filepath.Base(tmpDir)+"_runner")
+	containerConfig := sandboxConfig(cfg, `/usr/bin/time --format='{"exit_status":%x,"max_memory":%M,"real_time":%e}' -o /app/usage.json /app/output < /app/input.txt`)
+	containerConfig.AttachStdout = true
+	containerConfig.AttachStderr = true
+	resp, err := cli.ContainerCreate(runCtx, containerConfig, hostConfig, nil, nil, filepath.Base(tmpDir)+"_runner")
 	if err != nil {
 		return fmt.Errorf("create runner container error: %v", err)
 	}
@@ -113,70 +102,171 @@ func runCpp(ctx context.Context, task *model.Paste, cli *client.Client, tmpDir s
 		Force: true,
 	})
 
+	// Stream program output through a bounded capture instead of writing it
+	// to the tmpfs, so a chatty program is stopped before it fills /dev/shm.
+	attach, err := cli.ContainerAttach(runCtx, resp.ID, container.AttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("attach runner container error: %v", err)
+	}
+	defer attach.Close()
+
+	output := newOutputCapture(cfg.Limit.Output, cfg.Limit.Size)
+	copyDone := make(chan struct{})
+	go func() {
+		defer close(copyDone)
+		stdcopy.StdCopy(output.Stdout, output.Stderr, attach.Reader)
+	}()
+
 	// Start runner container execution
 	if err := cli.ContainerStart(runCtx, resp.ID, container.StartOptions{}); err != nil {
 		return fmt.Errorf("failed to start runner container: %v", err)
 	}
+	started := time.Now()
 
 	// 等待容器完成
 	statusCh, errCh := cli.ContainerWait(runCtx, resp.ID, container.WaitConditionNotRunning)
 
 	// 处理执行结果
+	killed := false
 	select {
 	case status := <-statusCh:
+		// Drain whatever is still buffered in the attach stream
+		<-copyDone
 
 		// 非零退出码表示运行时错误
-		if status.StatusCode == 137 {
-			task.Status = model.StatusMemoryLimitExceed
-		} else if status.StatusCode != 0 {
-			task.Status = model.StatusRuntimeError
+		if output.IsExceeded() {
+			task.Status = model.StatusOutputLimitExceed
 		} else {
-			task.Status = model.StatusCompleted
+			task.Status = runStatus(status.StatusCode)
 		}
+		// The shell passes on the exit status of the program
+		exitCode := int(status.StatusCode)
+		task.ExitCode = &exitCode
+	case <-output.Exceeded():
+		task.Status = model.StatusOutputLimitExceed
+		cli.ContainerKill(ctx, resp.ID, "KILL")
+		killed = true
 	case err := <-errCh:
 		return err
 	case <-runCtx.Done():
 		task.Status = model.StatusTimeLimitExceed
+		killed = true
 	}
+	elapsed := time.Since(started)
+	attach.Close()
+	<-copyDone
 
-	// Process execution results by reading output files
-	stdoutPath := filepath.Join(tmpDir, "stdout.txt")
-	stderrPath := filepath.Join(tmpDir, "stderr.txt")
-	usagePath := filepath.Join(tmpDir, "usage.json")
+	task.Stdout = output.Stdout.String()
+	task.Stderr = output.Stderr.String()
+	task.StdoutTruncated = output.Stdout.Truncated()
+	task.StderrTruncated = output.Stderr.Truncated()
 
-	// Read program output
-	if stdoutData, err := os.ReadFile(stdoutPath); err == nil {
-		stdoutData = stdoutData[:min(len(stdoutData), cfg.Limit.Size)]
-		task.Stdout = string(stdoutData)
+	if task.Status == model.StatusTimeLimitExceed {
+		// The deadline also covers creating the container
+		elapsed = max(elapsed, time.Duration(cfg.Limit.Time*float32(time.Second)))
 	}
-	if stderrData, err := os.ReadFile(stderrPath); err == nil {
-		stderrData = stderrData[:min(len(stderrData), cfg.Limit.Size)]
-		task.Stderr = string(stderrData)
+	if err := recordUsage(task, usagePath, killed, elapsed); err != nil {
+		log.Printf("Usage report error at ExecutionID: %s, error: %v", task.ID, err)
 	}
-	if usageData, err := os.ReadFile(usagePath); err == nil {
-		var usage Usage
-		json.Unmarshal(usageData, &usage)
-		task.MemoryUsageKb = int(usage.MaxMemory)
-		task.ExecutionTimeMs = int(usage.RealTime * 1000)
+	return nil
+}
+
+// recordUsage fills in the time and memory a run used from the report of
+// /usr/bin/time at usagePath. A run the worker killed leaves no report and
+// is charged the wall time it ran for instead, so that the longest runs
+// still count against the daily CPU quota. An empty report, left when
+// /usr/bin/time did not get to write one, records nothing.
+func recordUsage(task *model.Execution, usagePath string, killed bool, elapsed time.Duration) error {
+	if killed {
+		task.ExecutionTimeMs = int(elapsed.Milliseconds())
+		return nil
 	}
+
+	usageData, err := os.ReadFile(usagePath)
+	if err != nil {
+		return fmt.Errorf("read usage file error: %v", err)
+	}
+	// GNU time precedes the report with a line such as "Command exited
+	// with non-zero status 1" when the program fails
+	report := strings.TrimSpace(string(usageData))
+	if report == "" {
+		return nil
+	}
+	report = report[strings.LastIndex(report, "\n")+1:]
+	var usage Usage
+	if err := json.Unmarshal([]byte(report), &usage); err != nil {
+		return fmt.Errorf("malformed usage file %q: %v", usageData, err)
+	}
+	task.MemoryUsageKb = int(usage.MaxMemory)
+	task.ExecutionTimeMs = int(usage.RealTime * 1000)
 	return nil
 }
 
-func (w *Worker) RunCppTask(ctx context.Context, task *model.Paste, cli *client.Client) error {
+func (w *Worker) RunCppTask(ctx context.Context, task *model.Execution, cli *client.Client) error {
 	// 临时文件夹
-	tmpDir, err := os.MkdirTemp("/dev/shm/", "cpp_compile_")
+	tmpDir, err := os.MkdirTemp(tmpRoot, tmpDirPrefix(w.cfg))
 	if err != nil {
 		return fmt.Errorf("create temp dir error: %v", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	if err := compileCpp(ctx, task, cli, tmpDir, w.cfg); err != nil {
-		return err
+	// The sandboxed compiler runs as an unprivileged user and must be able
+	// to write the binary into the work directory
+	if err := os.Chmod(tmpDir, 0777); err != nil {
+		return fmt.Errorf("chmod temp dir error: %v", err)
 	}
 
-	if task.Status == model.StatusCompileError {
-		return nil
+	// 写入main.cpp
+	codePath := filepath.Join(tmpDir, "main.cpp")
+	if err := os.WriteFile(codePath, []byte(task.Code), 0644); err != nil {
+		return fmt.Errorf("write code file error: %v", err)
+	}
+
+	lang, ok := model.LookupLanguage(task.Language)
+	if !ok {
+		return fmt.Errorf("unknown language %q", task.Language)
+	}
+
+	// Record the exact compiler image, as the tag may move between runs
+	toolchain, err := w.toolchain(ctx, cli)
+	if err != nil {
+		return fmt.Errorf("inspect compiler image error: %v", err)
+	}
+	task.Toolchain = toolchain
+	buildCfg := *w.cfg
+	buildCfg.CompilerImage = toolchain
+
+	// Reuse a binary built from the same source by the same toolchain
+	var cacheKey string
+	if w.cache != nil {
+		cacheKey = compileCacheKey(task.Code, task.Language, lang.Flags, toolchain)
+		if compileLog, ok := w.cache.Get(cacheKey, filepath.Join(tmpDir, "output")); ok {
+			task.CompileLog = compileLog
+			task.CacheHit = true
+		}
+	}
+
+	if !task.CacheHit {
+		if err := compileCpp(ctx, task, cli, tmpDir, &buildCfg, builderHostConfig(tmpDir, &buildCfg, w.seccomp), lang.Flags); err != nil {
+			return err
+		}
+
+		if task.Status == model.StatusCompileError || task.Status == model.StatusResourceLimitExceed {
+			return nil
+		}
+
+		if w.cache != nil {
+			if err := w.cache.Put(cacheKey, filepath.Join(tmpDir, "output"), task.CompileLog); err != nil {
+				log.Printf("Compile cache store error at ExecutionID: %s, error: %v", task.ID, err)
+			}
+		}
 	}
 
-	return runCpp(ctx, task, cli, tmpDir, w.cfg)
+	runCfg := w.runConfig(task)
+	runCfg.CompilerImage = toolchain
+	return runCpp(ctx, task, cli, tmpDir, runCfg, runnerHostConfig(tmpDir, runCfg, w.seccomp))
 }
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"runbin/internal/model"
+	"runbin/internal/repository"
+)
+
+func TestRecordUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		status model.PasteStatus
+		// Report written by /usr/bin/time, if any
+		usage   string
+		killed  bool
+		elapsed time.Duration
+		wantMs  int
+		wantErr bool
+	}{
+		{
+			name:    "completed",
+			status:  model.StatusCompleted,
+			usage:   `{"exit_status":0,"max_memory":1024,"real_time":0.25}`,
+			elapsed: time.Second,
+			wantMs:  250,
+		},
+		{
+			name:    "runtime error",
+			status:  model.StatusRuntimeError,
+			usage:   "Command exited with non-zero status 1\n" + `{"exit_status":1,"max_memory":1024,"real_time":0.5}` + "\n",
+			elapsed: time.Second,
+			wantMs:  500,
+		},
+		{
+			name:    "no report",
+			status:  model.StatusMemoryLimitExceed,
+			elapsed: time.Second,
+		},
+		{
+			name:    "malformed report",
+			status:  model.StatusCompleted,
+			usage:   `{"exit_status":0,`,
+			elapsed: time.Second,
+			wantErr: true,
+		},
+		{
+			name:    "time limit exceeded",
+			status:  model.StatusTimeLimitExceed,
+			killed:  true,
+			elapsed: 2 * time.Second,
+			wantMs:  2000,
+		},
+		{
+			name:    "output limit exceeded",
+			status:  model.StatusOutputLimitExceed,
+			killed:  true,
+			elapsed: 300 * time.Millisecond,
+			wantMs:  300,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usagePath := filepath.Join(t.TempDir(), "usage.json")
+			if err := os.WriteFile(usagePath, []byte(tt.usage), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			store := repository.NewMemoryPasteStore()
+			task := &model.Execution{ID: "run-1", PasteID: "paste-1", Status: tt.status, RequestedBy: "user-1"}
+			if err := recordUsage(task, usagePath, tt.killed, tt.elapsed); (err != nil) != tt.wantErr {
+				t.Errorf("recordUsage error = %v, want error %v", err, tt.wantErr)
+			}
+			if task.ExecutionTimeMs != tt.wantMs {
+				t.Errorf("ExecutionTimeMs = %d, want %d", task.ExecutionTimeMs, tt.wantMs)
+			}
+
+			if err := store.UpdateExecution(task); err != nil {
+				t.Fatal(err)
+			}
+			used, err := store.GetCPUUsage("user-1", time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if used != int64(tt.wantMs) {
+				t.Errorf("daily CPU usage = %dms, want %dms", used, tt.wantMs)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compileCache is a content-addressed store of compiled binaries on local
+// disk. Every entry is a binary file named after its key plus a ".log" file
+// holding the compiler output. The least recently used entries are evicted
+// once the total size exceeds maxBytes.
+type compileCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	size    int64
+}
+
+type cacheEntry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+// compileCacheKey identifies a build by everything that can change its
+// output: the source, the language, the compiler flags and the exact
+// compiler image.
+func compileCacheKey(code, language string, flags []string, imageID string) string {
+	h := sha256.New()
+	for _, part := range []string{code, language, strings.Join(flags, " "), imageID} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Age after which a temporary file left in the cache directory is assumed
+// to be abandoned
+const staleTempAge = time.Minute
+
+func newCompileCache(dir string, maxBytes int64) (*compileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create cache dir error: %v", err)
+	}
+
+	c := &compileCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*cacheEntry),
+	}
+
+	// Pick up entries left by a previous run
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read cache dir error: %v", err)
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(name, ".") {
+			// Temporary files of a Put interrupted by a crash. Newer ones
+			// may belong to another worker sharing the directory.
+			if time.Since(info.ModTime()) > staleTempAge {
+				os.Remove(filepath.Join(dir, name))
+			}
+			continue
+		}
+		key := strings.TrimSuffix(name, ".log")
+		entry, ok := c.entries[key]
+		if !ok {
+			entry = &cacheEntry{}
+			c.entries[key] = entry
+		}
+		entry.size += info.Size()
+		if info.ModTime().After(entry.lastUsed) {
+			entry.lastUsed = info.ModTime()
+		}
+		c.size += info.Size()
+	}
+
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+
+	return c, nil
+}
+
+// Get copies the cached binary for key to dst and returns the cached
+// compile log. ok is false on a miss.
+func (c *compileCache) Get(key, dst string) (compileLog string, ok bool) {
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	if found {
+		entry.lastUsed = time.Now()
+	}
+	c.mu.Unlock()
+	if !found {
+		return "", false
+	}
+
+	binPath := filepath.Join(c.dir, key)
+	if err := copyFile(binPath, dst, 0755); err != nil {
+		log.Printf("Compile cache read %s error: %v", key, err)
+		c.remove(key)
+		return "", false
+	}
+	logData, err := os.ReadFile(binPath + ".log")
+	if err != nil {
+		log.Printf("Compile cache read %s log error: %v", key, err)
+		c.remove(key)
+		return "", false
+	}
+
+	// The modification time doubles as the last-use time across restarts
+	now := time.Now()
+	os.Chtimes(binPath, now, now)
+
+	return string(logData), true
+}
+
+// Put stores the binary at src and its compile log under key.
+func (c *compileCache) Put(key, src, compileLog string) error {
+	binPath := filepath.Join(c.dir, key)
+
+	// Write under temporary names and rename, so a concurrent Get never
+	// sees a partially written entry
+	tmp, err := os.CreateTemp(c.dir, "."+key+"_")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	tmpBin := tmp.Name()
+	if err := copyFile(src, tmpBin, 0755); err != nil {
+		os.Remove(tmpBin)
+		return err
+	}
+	tmpLog := tmpBin + ".log"
+	if err := os.WriteFile(tmpLog, []byte(compileLog), 0644); err != nil {
+		os.Remove(tmpBin)
+		return err
+	}
+	if err := os.Rename(tmpLog, binPath+".log"); err != nil {
+		os.Remove(tmpBin)
+		os.Remove(tmpLog)
+		return err
+	}
+	if err := os.Rename(tmpBin, binPath); err != nil {
+		os.Remove(tmpBin)
+		return err
+	}
+
+	info, err := os.Stat(binPath)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.size
+	}
+	size := info.Size() + int64(len(compileLog))
+	c.entries[key] = &cacheEntry{size: size, lastUsed: time.Now()}
+	c.size += size
+	c.evict()
+	return nil
+}
+
+func (c *compileCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+}
+
+func (c *compileCache) removeLocked(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	binPath := filepath.Join(c.dir, key)
+	os.Remove(binPath)
+	os.Remove(binPath + ".log")
+	c.size -= entry.size
+	delete(c.entries, key)
+}
+
+// evict drops the least recently used entries until the cache fits in
+// maxBytes. The caller must hold c.mu.
+func (c *compileCache) evict() {
+	if c.size <= c.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].lastUsed.Before(c.entries[keys[j]].lastUsed)
+	})
+
+	for _, key := range keys {
+		if c.size <= c.maxBytes {
+			break
+		}
+		c.removeLocked(key)
+	}
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// The file mode passed to OpenFile is subject to the umask
+	return os.Chmod(dst, perm)
+}
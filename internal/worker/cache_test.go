@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCompileCacheRemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, ".abc_123")
+	fresh := filepath.Join(dir, ".def_456")
+	for _, path := range []string{stale, fresh} {
+		if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newCompileCache(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temporary file was kept: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("recent temporary file was removed: %v", err)
+	}
+	if len(c.entries) != 0 || c.size != 0 {
+		t.Errorf("temporary files were loaded as entries: %d entries, %d bytes", len(c.entries), c.size)
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	// Every entry is a 100 byte binary and a 5 byte log, so 250 bytes
+	// hold two of them
+	const entrySize = 105
+
+	tests := []struct {
+		name     string
+		maxBytes int64
+		// "put k" stores entry k, "get k" expects a hit on it and "miss k"
+		// a miss
+		steps []string
+	}{
+		{
+			name:     "miss on an empty cache",
+			maxBytes: 250,
+			steps:    []string{"miss a"},
+		},
+		{
+			name:     "put then get",
+			maxBytes: 250,
+			steps:    []string{"put a", "get a", "miss b"},
+		},
+		{
+			name:     "least recently stored is evicted",
+			maxBytes: 250,
+			steps:    []string{"put a", "put b", "put c", "miss a", "get b", "get c"},
+		},
+		{
+			name:     "get makes an entry recent",
+			maxBytes: 250,
+			steps:    []string{"put a", "put b", "get a", "put c", "miss b", "get a", "get c"},
+		},
+		{
+			name:     "put replaces an entry",
+			maxBytes: 250,
+			steps:    []string{"put a", "put a", "put b", "get a", "get b"},
+		},
+		{
+			name:     "entry larger than the cache",
+			maxBytes: entrySize - 1,
+			steps:    []string{"put a", "miss a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			work := t.TempDir()
+			c, err := newCompileCache(dir, tt.maxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, step := range tt.steps {
+				op, key, _ := strings.Cut(step, " ")
+				binary := strings.Repeat(key, 100)
+				switch op {
+				case "put":
+					src := filepath.Join(work, "output")
+					if err := os.WriteFile(src, []byte(binary), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := c.Put(key, src, "log "+key); err != nil {
+						t.Fatalf("%s: %v", step, err)
+					}
+				case "get", "miss":
+					dst := filepath.Join(work, "cached")
+					os.Remove(dst)
+					compileLog, ok := c.Get(key, dst)
+					if ok != (op == "get") {
+						t.Fatalf("%s: hit = %v", step, ok)
+					}
+					if !ok {
+						continue
+					}
+					data, err := os.ReadFile(dst)
+					if err != nil || string(data) != binary {
+						t.Errorf("%s: binary = %q, %v, want the stored one", step, data, err)
+					}
+					if info, err := os.Stat(dst); err != nil || info.Mode().Perm()&0100 == 0 {
+						t.Errorf("%s: binary is not executable", step)
+					}
+					if compileLog != "log "+key {
+						t.Errorf("%s: compile log = %q, want %q", step, compileLog, "log "+key)
+					}
+				}
+				// Keeps the last-use times of consecutive steps apart
+				time.Sleep(time.Millisecond)
+			}
+
+			if c.size > tt.maxBytes {
+				t.Errorf("size = %d, want at most %d", c.size, tt.maxBytes)
+			}
+			var onDisk int64
+			files, _ := os.ReadDir(dir)
+			for _, f := range files {
+				info, _ := f.Info()
+				onDisk += info.Size()
+			}
+			if onDisk != c.size {
+				t.Errorf("size = %d, but the files take %d bytes", c.size, onDisk)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+)
+
+var errOutputLimitExceeded = errors.New("output limit exceeded")
+
+// outputCapture bounds the total number of bytes a program may write to
+// stdout and stderr. Once the limit is crossed, Exceeded is closed so the
+// caller can kill the container.
+type outputCapture struct {
+	mu       sync.Mutex
+	limit    int
+	written  int
+	exceeded chan struct{}
+	once     sync.Once
+
+	Stdout *cappedStream
+	Stderr *cappedStream
+}
+
+// cappedStream keeps at most keep bytes of a single stream and records
+// whether anything beyond that was discarded.
+type cappedStream struct {
+	capture   *outputCapture
+	buf       bytes.Buffer
+	keep      int
+	truncated bool
+}
+
+func newOutputCapture(limit, keep int) *outputCapture {
+	c := &outputCapture{
+		limit:    limit,
+		exceeded: make(chan struct{}),
+	}
+	c.Stdout = &cappedStream{capture: c, keep: keep}
+	c.Stderr = &cappedStream{capture: c, keep: keep}
+	return c
+}
+
+func (c *outputCapture) Exceeded() <-chan struct{} {
+	return c.exceeded
+}
+
+func (c *outputCapture) IsExceeded() bool {
+	select {
+	case <-c.exceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// account adds n bytes to the shared counter and reports whether the
+// limit still holds.
+func (c *outputCapture) account(n int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written += n
+	if c.written > c.limit {
+		c.once.Do(func() { close(c.exceeded) })
+		return false
+	}
+	return true
+}
+
+func (s *cappedStream) Write(p []byte) (int, error) {
+	if room := s.keep - s.buf.Len(); room > 0 {
+		s.buf.Write(p[:min(len(p), room)])
+		if len(p) > room {
+			s.truncated = true
+		}
+	} else if len(p) > 0 {
+		s.truncated = true
+	}
+
+	if !s.capture.account(len(p)) {
+		return len(p), errOutputLimitExceeded
+	}
+	return len(p), nil
+}
+
+func (s *cappedStream) String() string {
+	return s.buf.String()
+}
+
+func (s *cappedStream) Truncated() bool {
+	return s.truncated
+}
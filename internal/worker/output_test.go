@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOutputCapture(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		keep  int
+		// Writes in order, to stdout unless prefixed with "err:"
+		writes []string
+
+		stdout, stderr                   string
+		stdoutTruncated, stderrTruncated bool
+		exceeded                         bool
+	}{
+		{
+			name:   "within both limits",
+			limit:  100,
+			keep:   10,
+			writes: []string{"hello", "err:oops"},
+			stdout: "hello",
+			stderr: "oops",
+		},
+		{
+			name:            "stream kept up to its cap",
+			limit:           100,
+			keep:            4,
+			writes:          []string{"hel", "lo", "err:oops"},
+			stdout:          "hell",
+			stderr:          "oops",
+			stdoutTruncated: true,
+		},
+		{
+			name:            "writes after the cap is full",
+			limit:           100,
+			keep:            2,
+			writes:          []string{"ab", "err:cd", "e", "err:f"},
+			stdout:          "ab",
+			stderr:          "cd",
+			stdoutTruncated: true,
+			stderrTruncated: true,
+		},
+		{
+			name:   "total exactly at the limit",
+			limit:  8,
+			keep:   10,
+			writes: []string{"four", "err:four"},
+			stdout: "four",
+			stderr: "four",
+		},
+		{
+			name:            "total over the limit",
+			limit:           8,
+			keep:            4,
+			writes:          []string{"four", "err:four", "x"},
+			stdout:          "four",
+			stderr:          "four",
+			stdoutTruncated: true,
+			exceeded:        true,
+		},
+		{
+			name:     "both streams count towards the limit",
+			limit:    5,
+			keep:     10,
+			writes:   []string{"abc", "err:def"},
+			stdout:   "abc",
+			stderr:   "def",
+			exceeded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newOutputCapture(tt.limit, tt.keep)
+			for _, w := range tt.writes {
+				stream := c.Stdout
+				if rest, ok := strings.CutPrefix(w, "err:"); ok {
+					stream, w = c.Stderr, rest
+				}
+				n, err := stream.Write([]byte(w))
+				if n != len(w) {
+					t.Errorf("Write(%q) = %d, want %d", w, n, len(w))
+				}
+				if err != nil && !errors.Is(err, errOutputLimitExceeded) {
+					t.Errorf("Write(%q) error = %v", w, err)
+				}
+			}
+
+			if got := c.Stdout.String(); got != tt.stdout {
+				t.Errorf("stdout = %q, want %q", got, tt.stdout)
+			}
+			if got := c.Stderr.String(); got != tt.stderr {
+				t.Errorf("stderr = %q, want %q", got, tt.stderr)
+			}
+			if got := c.Stdout.Truncated(); got != tt.stdoutTruncated {
+				t.Errorf("stdout truncated = %v, want %v", got, tt.stdoutTruncated)
+			}
+			if got := c.Stderr.Truncated(); got != tt.stderrTruncated {
+				t.Errorf("stderr truncated = %v, want %v", got, tt.stderrTruncated)
+			}
+			if got := c.IsExceeded(); got != tt.exceeded {
+				t.Errorf("IsExceeded = %v, want %v", got, tt.exceeded)
+			}
+		})
+	}
+}
+
+func TestOutputCaptureExceededOnce(t *testing.T) {
+	c := newOutputCapture(10, 4)
+	select {
+	case <-c.Exceeded():
+		t.Fatal("Exceeded closed before any output")
+	default:
+	}
+
+	// Both streams keep writing past the limit concurrently; closing the
+	// channel twice would panic
+	var wg sync.WaitGroup
+	for _, stream := range []*cappedStream{c.Stdout, c.Stderr} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				stream.Write([]byte("xyz"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-c.Exceeded():
+	default:
+		t.Fatal("Exceeded not closed after the limit was crossed")
+	}
+	if _, err := c.Stdout.Write([]byte("more")); !errors.Is(err, errOutputLimitExceeded) {
+		t.Errorf("Write after the limit = %v, want %v", err, errOutputLimitExceeded)
+	}
+	if !c.IsExceeded() {
+		t.Error("IsExceeded = false after the limit was crossed")
+	}
+}
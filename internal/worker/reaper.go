@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"runbin/internal/config"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// Directory holding the per-task work directories
+	tmpRoot = "/dev/shm/"
+
+	labelManaged = "runbin.managed"
+	labelWorker  = "runbin.worker"
+)
+
+// containerLabels marks a container as owned by RunBin and by this worker,
+// so the reaper can find it again after a crash.
+func containerLabels(cfg *config.WorkerConfig) map[string]string {
+	return map[string]string{
+		labelManaged: "true",
+		labelWorker:  cfg.Name,
+	}
+}
+
+// tmpDirPrefix returns the work directory prefix for this worker. The name
+// is hashed because it may contain characters that are not valid in paths
+// or container names, and so that one worker's prefix never prefixes
+// another's.
+func tmpDirPrefix(cfg *config.WorkerConfig) string {
+	sum := sha256.Sum256([]byte(cfg.Name))
+	return "cpp_compile_" + hex.EncodeToString(sum[:4]) + "_"
+}
+
+// reap periodically removes this worker's containers and work directories
+// that outlived MaxAge, e.g. because a removal failed mid-task. The
+// interval must be positive.
+func (w *Worker) reap(ctx context.Context, cli *client.Client) {
+	ticker := time.NewTicker(time.Duration(w.cfg.Reaper.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.sweep(ctx, cli, time.Duration(w.cfg.Reaper.MaxAge)*time.Second)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sweep removes the containers and work directories of workers with this
+// worker's name that are older than maxAge.
+func (w *Worker) sweep(ctx context.Context, cli *client.Client, maxAge time.Duration) {
+	deadline := time.Now().Add(-maxAge)
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", labelManaged+"=true"),
+			filters.Arg("label", labelWorker+"="+w.cfg.Name),
+		),
+	})
+	if err != nil {
+		log.Printf("Reaper list containers error: %v", err)
+	} else {
+		for _, c := range containers {
+			if time.Unix(c.Created, 0).After(deadline) {
+				continue
+			}
+			if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+				log.Printf("Reaper remove container %s error: %v", c.ID, err)
+				continue
+			}
+			log.Printf("Reaper removed stale container %s", strings.Join(c.Names, ","))
+		}
+	}
+
+	entries, err := os.ReadDir(tmpRoot)
+	if err != nil {
+		log.Printf("Reaper read %s error: %v", tmpRoot, err)
+		return
+	}
+	prefix := tmpDirPrefix(w.cfg)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(deadline) {
+			continue
+		}
+		path := filepath.Join(tmpRoot, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			log.Printf("Reaper remove %s error: %v", path, err)
+			continue
+		}
+		log.Printf("Reaper removed stale directory %s", path)
+	}
+}
@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"runbin/internal/config"
+	"runbin/internal/model"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+// Exit code of a process killed by SIGXFSZ (128 + 25), raised when the
+// file-size ulimit is exceeded.
+const exitCodeFileSizeExceeded = 153
+
+// sandboxConfig builds the Config of a builder or runner container, which
+// runs cmd through the shell as the unprivileged sandbox user.
+func sandboxConfig(cfg *config.WorkerConfig, cmd string) *container.Config {
+	return &container.Config{
+		Image:  cfg.CompilerImage,
+		User:   cfg.Security.User,
+		Labels: containerLabels(cfg),
+		Cmd:    []string{"sh", "-c", cmd},
+	}
+}
+
+// sandboxHostConfig builds the HostConfig shared by the builder and runner
+// containers. The root filesystem is read-only; the only writable places
+// are the given binds and a size-limited /tmp. All capabilities are
+// dropped and privilege escalation is disabled.
+func sandboxHostConfig(binds []string, cfg *config.WorkerConfig, seccomp string) *container.HostConfig {
+	pids := int64(cfg.Limit.Pids)
+
+	securityOpt := []string{"no-new-privileges"}
+	if seccomp != "" {
+		securityOpt = append(securityOpt, "seccomp="+seccomp)
+	}
+
+	return &container.HostConfig{
+		Binds: binds,
+		Resources: container.Resources{
+			Memory:    int64(cfg.Limit.Memory * 1024 * 1024),
+			CPUQuota:  int64(cfg.Limit.Cpu * 100000),
+			PidsLimit: &pids,
+			Ulimits: []*units.Ulimit{
+				{Name: "fsize", Soft: int64(cfg.Limit.FileSize), Hard: int64(cfg.Limit.FileSize)},
+				{Name: "nofile", Soft: int64(cfg.Limit.NoFile), Hard: int64(cfg.Limit.NoFile)},
+			},
+		},
+		NetworkMode:    "none",
+		ReadonlyRootfs: true,
+		Tmpfs: map[string]string{
+			"/tmp": fmt.Sprintf("rw,nosuid,nodev,size=%dm", cfg.Limit.Tmpfs),
+		},
+		CapDrop:     []string{"ALL"},
+		SecurityOpt: securityOpt,
+		Runtime:     cfg.Security.Runtime,
+	}
+}
+
+// builderHostConfig mounts the work directory writable so the compiler can
+// emit the binary and its log.
+func builderHostConfig(tmpDir string, cfg *config.WorkerConfig, seccomp string) *container.HostConfig {
+	return sandboxHostConfig([]string{tmpDir + ":/app"}, cfg, seccomp)
+}
+
+// runnerHostConfig mounts the source and binary read-only. usage.json is the
+// only file the runner may write, so it is bound on top of the read-only
+// work directory.
+func runnerHostConfig(tmpDir string, cfg *config.WorkerConfig, seccomp string) *container.HostConfig {
+	return sandboxHostConfig([]string{
+		tmpDir + ":/app:ro",
+		filepath.Join(tmpDir, "usage.json") + ":/app/usage.json",
+	}, cfg, seccomp)
+}
+
+// loadSeccompProfile reads a seccomp profile and returns it compacted, as
+// the Docker API expects the profile content rather than a path. An empty
+// path keeps the runtime's default profile.
+func loadSeccompProfile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read seccomp profile error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid seccomp profile %s: %v", path, err)
+	}
+	return buf.String(), nil
+}
+
+// runStatus maps the exit code of the runner container to a paste status.
+// Only the file-size limit kills the program with a signal of its own.
+// Reaching the pids or nofile limit merely makes fork or open fail, which
+// the program sees as an ordinary error, so such runs are reported as
+// runtime errors.
+func runStatus(exitCode int64) model.PasteStatus {
+	switch {
+	case exitCode == 0:
+		return model.StatusCompleted
+	case exitCode == 137:
+		return model.StatusMemoryLimitExceed
+	case exitCode == exitCodeFileSizeExceeded:
+		return model.StatusResourceLimitExceed
+	default:
+		return model.StatusRuntimeError
+	}
+}
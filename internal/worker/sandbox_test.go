@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"runbin/internal/config"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func testWorkerConfig(runtime string) *config.WorkerConfig {
+	return &config.WorkerConfig{
+		Limit: config.LimitConfig{
+			Cpu:      1,
+			Memory:   512,
+			Pids:     64,
+			FileSize: 1 << 20,
+			NoFile:   64,
+			Tmpfs:    64,
+		},
+		Security: config.SecurityConfig{
+			User:    "65534:65534",
+			Runtime: runtime,
+		},
+		Name:          "test",
+		CompilerImage: "cpp_gcc-latest:latest",
+	}
+}
+
+func TestSandboxHostConfig(t *testing.T) {
+	const seccomp = `{"defaultAction":"SCMP_ACT_ERRNO"}`
+
+	tests := []struct {
+		name    string
+		build   func(tmpDir string, cfg *config.WorkerConfig, seccomp string) *container.HostConfig
+		runtime string
+		seccomp string
+		// Binds expected for a work directory of /work
+		binds []string
+	}{
+		{
+			name:    "builder",
+			build:   builderHostConfig,
+			seccomp: seccomp,
+			binds:   []string{"/work:/app"},
+		},
+		{
+			name:    "runner",
+			build:   runnerHostConfig,
+			seccomp: seccomp,
+			binds:   []string{"/work:/app:ro", "/work/usage.json:/app/usage.json"},
+		},
+		{
+			name:  "runner with default seccomp profile",
+			build: runnerHostConfig,
+			binds: []string{"/work:/app:ro", "/work/usage.json:/app/usage.json"},
+		},
+		{
+			name:    "runner with gVisor",
+			build:   runnerHostConfig,
+			runtime: "runsc",
+			seccomp: seccomp,
+			binds:   []string{"/work:/app:ro", "/work/usage.json:/app/usage.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testWorkerConfig(tt.runtime)
+			hc := tt.build("/work", cfg, tt.seccomp)
+
+			if !slices.Equal(hc.CapDrop, []string{"ALL"}) {
+				t.Errorf("CapDrop = %v, want [ALL]", hc.CapDrop)
+			}
+			if len(hc.CapAdd) != 0 {
+				t.Errorf("CapAdd = %v, want none", hc.CapAdd)
+			}
+			if !slices.Contains(hc.SecurityOpt, "no-new-privileges") {
+				t.Errorf("SecurityOpt = %v, want no-new-privileges", hc.SecurityOpt)
+			}
+			hasSeccomp := slices.ContainsFunc(hc.SecurityOpt, func(opt string) bool {
+				return strings.HasPrefix(opt, "seccomp=")
+			})
+			if tt.seccomp == "" && hasSeccomp {
+				t.Errorf("SecurityOpt = %v, want the runtime's default seccomp profile", hc.SecurityOpt)
+			}
+			if tt.seccomp != "" && !slices.Contains(hc.SecurityOpt, "seccomp="+tt.seccomp) {
+				t.Errorf("SecurityOpt = %v, want seccomp=%s", hc.SecurityOpt, tt.seccomp)
+			}
+			if hc.Privileged {
+				t.Error("Privileged = true")
+			}
+			if !hc.ReadonlyRootfs {
+				t.Error("ReadonlyRootfs = false")
+			}
+			if hc.NetworkMode != "none" {
+				t.Errorf("NetworkMode = %q, want none", hc.NetworkMode)
+			}
+			if !slices.Equal(hc.Binds, tt.binds) {
+				t.Errorf("Binds = %v, want %v", hc.Binds, tt.binds)
+			}
+			if hc.Runtime != tt.runtime {
+				t.Errorf("Runtime = %q, want %q", hc.Runtime, tt.runtime)
+			}
+			if hc.PidsLimit == nil || *hc.PidsLimit != int64(cfg.Limit.Pids) {
+				t.Errorf("PidsLimit = %v, want %d", hc.PidsLimit, cfg.Limit.Pids)
+			}
+			if got := hc.Tmpfs["/tmp"]; !strings.Contains(got, "size=64m") || !strings.Contains(got, "nosuid") {
+				t.Errorf("Tmpfs[/tmp] = %q, want a nosuid mount of 64m", got)
+			}
+		})
+	}
+}
+
+func TestSandboxConfigUser(t *testing.T) {
+	cfg := testWorkerConfig("")
+	c := sandboxConfig(cfg, "true")
+
+	if c.User != cfg.Security.User {
+		t.Errorf("User = %q, want %q", c.User, cfg.Security.User)
+	}
+	uid, _, _ := strings.Cut(c.User, ":")
+	if uid == "" || uid == "0" || uid == "root" {
+		t.Errorf("User = %q, want a non-root user", c.User)
+	}
+	if !slices.Equal(c.Cmd, []string{"sh", "-c", "true"}) {
+		t.Errorf("Cmd = %v, want [sh -c true]", c.Cmd)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"runbin/internal/config"
@@ -14,14 +15,36 @@ import (
 )
 
 type Worker struct {
-	repo repository.PasteRepository
-	cfg  *config.WorkerConfig
+	repo    repository.PasteRepository
+	cfg     *config.WorkerConfig
+	seccomp string
+	cache   *compileCache
+
+	// ID of the compiler image, as last looked up at toolchainAt
+	toolchainMu sync.Mutex
+	toolchainID string
+	toolchainAt time.Time
 }
 
 func NewWorker(repo repository.PasteRepository, cfg *config.WorkerConfig) *Worker {
+	seccomp, err := loadSeccompProfile(cfg.Security.SeccompProfile)
+	if err != nil {
+		log.Fatalf("Failed to load seccomp profile: %v", err)
+	}
+
+	var cache *compileCache
+	if cfg.Cache.Enabled {
+		cache, err = newCompileCache(cfg.Cache.Dir, int64(cfg.Cache.MaxSize)*1024*1024)
+		if err != nil {
+			log.Fatalf("Failed to open compile cache: %v", err)
+		}
+	}
+
 	return &Worker{
-		repo: repo,
-		cfg:  cfg,
+		repo:    repo,
+		cfg:     cfg,
+		seccomp: seccomp,
+		cache:   cache,
 	}
 }
 
@@ -29,6 +52,21 @@ func (w *Worker) Run() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		log.Fatalf("Failed to create docker client: %v", err)
+	}
+	defer cli.Close()
+
+	// Replicas may share the name of this worker, so even at startup only
+	// what has outlived any task is removed
+	w.sweep(ctx, cli, time.Duration(w.cfg.Reaper.MaxAge)*time.Second)
+	if w.cfg.Reaper.Interval > 0 {
+		go w.reap(ctx, cli)
+	} else {
+		log.Printf("Periodic reaping is disabled")
+	}
+
 	// run n process
 	for range w.cfg.Process {
 		go w.processTasks(ctx)
@@ -57,10 +95,10 @@ func (w *Worker) processTasks(ctx context.Context) {
 					continue
 				}
 				if err := w.handleTask(ctx, task, cli); err != nil {
-					log.Printf("Worker error at PasteID: %s, error: %v\n", task.ID, err)
+					log.Printf("Worker error at ExecutionID: %s, error: %v\n", task.ID, err)
 				}
-				if err := w.repo.Update(task); err != nil {
-					log.Printf("Update error at PasteID: %s, error: %v\n", task.ID, err)
+				if err := w.repo.UpdateExecution(task); err != nil {
+					log.Printf("Update error at ExecutionID: %s, error: %v\n", task.ID, err)
 				}
 			} else {
 				log.Printf("Worker get task error: %v\n", err)
@@ -71,12 +109,14 @@ func (w *Worker) processTasks(ctx context.Context) {
 	}
 }
 
-func (w *Worker) handleTask(ctx context.Context, task *model.Paste, cli *client.Client) error {
-	log.Printf("Hangling task %s for language %s", task.ID, task.Language)
+func (w *Worker) handleTask(ctx context.Context, task *model.Execution, cli *client.Client) error {
+	log.Printf("Hangling task %s of paste %s for language %s", task.ID, task.PasteID, task.Language)
 
 	task.Status = model.StatusRunning
 	task.BackEnd = w.cfg.Name
-	w.repo.Update(task)
+	task.CacheHit = false
+	task.ExitCode = nil
+	w.repo.UpdateExecution(task)
 
 	var err error
 
@@ -96,3 +136,39 @@ func (w *Worker) handleTask(ctx context.Context, task *model.Paste, cli *client.
 
 	return err
 }
+
+// How long the resolved compiler image is trusted before its tag is looked
+// up again
+const toolchainTTL = time.Minute
+
+// toolchain returns the ID of the compiler image. The tag is resolved at
+// most once per toolchainTTL rather than for every task; containers are
+// created from the returned ID, so the toolchain recorded for a task is
+// always the one that built it even if the tag moves in between.
+func (w *Worker) toolchain(ctx context.Context, cli *client.Client) (string, error) {
+	w.toolchainMu.Lock()
+	defer w.toolchainMu.Unlock()
+	if w.toolchainID != "" && time.Since(w.toolchainAt) < toolchainTTL {
+		return w.toolchainID, nil
+	}
+
+	image, err := cli.ImageInspect(ctx, w.cfg.CompilerImage)
+	if err != nil {
+		return "", err
+	}
+	w.toolchainID, w.toolchainAt = image.ID, time.Now()
+	return image.ID, nil
+}
+
+// runConfig returns the configuration the runner uses for task. Limits
+// requested by the task may lower the worker's limits but never raise them.
+func (w *Worker) runConfig(task *model.Execution) *config.WorkerConfig {
+	cfg := *w.cfg
+	if task.TimeLimit > 0 && task.TimeLimit < cfg.Limit.Time {
+		cfg.Limit.Time = task.TimeLimit
+	}
+	if task.MemoryLimit > 0 && task.MemoryLimit < cfg.Limit.Memory {
+		cfg.Limit.Memory = task.MemoryLimit
+	}
+	return &cfg
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS stdout_truncated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS stderr_truncated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE pastes ALTER COLUMN status TYPE VARCHAR(32);
+
+-- +goose Down
+-- Statuses that do not fit the original column are reported as they were
+-- before output limits existed, or as unknown errors
+UPDATE pastes SET status = 'runtime error' WHERE status = 'output limit exceeded';
+UPDATE pastes SET status = 'unknown error' WHERE length(status) > 20;
+ALTER TABLE pastes ALTER COLUMN status TYPE VARCHAR(20);
+ALTER TABLE pastes DROP COLUMN stdout_truncated;
+ALTER TABLE pastes DROP COLUMN stderr_truncated;
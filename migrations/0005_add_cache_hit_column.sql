@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS cache_hit BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE pastes DROP COLUMN cache_hit;
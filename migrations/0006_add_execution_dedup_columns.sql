@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS execution_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS toolchain VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS cached_from VARCHAR(36) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_pastes_execution_hash ON pastes (execution_hash, updated_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_pastes_execution_hash;
+ALTER TABLE pastes DROP COLUMN cached_from;
+ALTER TABLE pastes DROP COLUMN toolchain;
+ALTER TABLE pastes DROP COLUMN execution_hash;
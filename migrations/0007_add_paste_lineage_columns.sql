@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS parent_id VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_pastes_parent_id ON pastes (parent_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_pastes_parent_id;
+ALTER TABLE pastes DROP COLUMN revision;
+ALTER TABLE pastes DROP COLUMN parent_id;
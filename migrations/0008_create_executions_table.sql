@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS executions (
+    id VARCHAR(36) PRIMARY KEY,
+    paste_id VARCHAR(36) NOT NULL REFERENCES pastes (id) ON DELETE CASCADE,
+    stdin TEXT NOT NULL DEFAULT '',
+    time_limit REAL NOT NULL DEFAULT 0,
+    memory_limit INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(32) NOT NULL,
+    stdout TEXT NOT NULL DEFAULT '',
+    stderr TEXT NOT NULL DEFAULT '',
+    stdout_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    stderr_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    compile_log TEXT NOT NULL DEFAULT '',
+    execution_time_ms INTEGER NOT NULL DEFAULT 0,
+    memory_usage_kb INTEGER NOT NULL DEFAULT 0,
+    cache_hit BOOLEAN NOT NULL DEFAULT FALSE,
+    toolchain VARCHAR(128) NOT NULL DEFAULT '',
+    backend VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_executions_paste_id ON executions (paste_id, created_at);
+
+-- The queue now holds execution IDs. The initial run of a paste shares the
+-- paste's ID, so pastes that were already run or queued get their initial
+-- execution backfilled under the same ID.
+INSERT INTO executions (
+    id, paste_id, stdin, status, stdout, stderr,
+    stdout_truncated, stderr_truncated, compile_log,
+    execution_time_ms, memory_usage_kb, cache_hit, toolchain, backend,
+    created_at, updated_at
+)
+SELECT
+    id, id, COALESCE(stdin, ''), status, COALESCE(stdout, ''), COALESCE(stderr, ''),
+    stdout_truncated, stderr_truncated, compile_log,
+    COALESCE(execution_time_ms, 0), COALESCE(memory_usage_kb, 0), cache_hit, toolchain, COALESCE(backend, ''),
+    created_at, COALESCE(updated_at, created_at)
+FROM pastes
+WHERE COALESCE(backend, '') <> '' OR status <> 'completed'
+ON CONFLICT (id) DO NOTHING;
+
+-- +goose Down
+DROP TABLE executions;
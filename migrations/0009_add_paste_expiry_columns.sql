@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_pastes_expires_at ON pastes (expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_pastes_expires_at;
+ALTER TABLE pastes DROP COLUMN burn_after_reading;
+ALTER TABLE pastes DROP COLUMN expires_at;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE pastes DROP COLUMN token_hash;
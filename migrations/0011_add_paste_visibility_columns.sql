@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS visibility VARCHAR(16) NOT NULL DEFAULT 'public';
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE pastes DROP COLUMN password_hash;
+ALTER TABLE pastes DROP COLUMN visibility;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(128) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
+
+-- CPU time used per user and UTC day, kept apart from executions so that
+-- deleting pastes does not give quota back
+CREATE TABLE IF NOT EXISTS cpu_usage (
+    user_id VARCHAR(36) NOT NULL,
+    day DATE NOT NULL,
+    execution_time_ms BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+ALTER TABLE executions ADD COLUMN IF NOT EXISTS requested_by VARCHAR(36) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE executions DROP COLUMN requested_by;
+DROP TABLE cpu_usage;
+DROP TABLE rate_limit_buckets;
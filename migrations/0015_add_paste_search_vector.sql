@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', code)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_pastes_search_vector ON pastes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_pastes_listing ON pastes (created_at DESC, id DESC) WHERE visibility = 'public';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_pastes_listing;
+DROP INDEX IF EXISTS idx_pastes_search_vector;
+ALTER TABLE pastes DROP COLUMN search_vector;
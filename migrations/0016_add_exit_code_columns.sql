@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS exit_code INTEGER;
+ALTER TABLE executions ADD COLUMN IF NOT EXISTS exit_code INTEGER;
+
+-- +goose Down
+ALTER TABLE executions DROP COLUMN exit_code;
+ALTER TABLE pastes DROP COLUMN exit_code;
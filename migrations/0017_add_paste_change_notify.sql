@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_paste_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('paste_changes', NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS pastes_notify_change ON pastes;
+CREATE TRIGGER pastes_notify_change AFTER UPDATE OF status ON pastes
+    FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION notify_paste_change();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS pastes_notify_change ON pastes;
+DROP FUNCTION IF EXISTS notify_paste_change();
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE pastes ADD COLUMN IF NOT EXISTS callback_url TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    -- A paste is notified once, however often its result is stored
+    paste_id VARCHAR(36) NOT NULL UNIQUE REFERENCES pastes (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+ALTER TABLE pastes DROP COLUMN callback_url;
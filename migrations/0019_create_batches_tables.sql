@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS batches (
+    id VARCHAR(36) PRIMARY KEY,
+    owner_id VARCHAR(36) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS batch_items (
+    batch_id VARCHAR(36) NOT NULL REFERENCES batches (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    paste_id VARCHAR(36) NOT NULL REFERENCES pastes (id) ON DELETE CASCADE,
+    PRIMARY KEY (batch_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_items_paste_id ON batch_items (paste_id);
+
+-- +goose Down
+DROP TABLE batch_items;
+DROP TABLE batches;
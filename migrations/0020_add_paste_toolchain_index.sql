@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_pastes_language_toolchain ON pastes (language, updated_at DESC) WHERE toolchain <> '';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_pastes_language_toolchain;
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// SubmitBatch creates several pastes at once. Either all of them are
+// created or, if any item is rejected, none is.
+func (c *Client) SubmitBatch(ctx context.Context, items []SubmitRequest) (*BatchCreated, error) {
+	var created BatchCreated
+	req := &BatchRequest{Items: items}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/batches", req, &created, nil); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Batch returns the progress of a batch and the status of its pastes.
+func (c *Client) Batch(ctx context.Context, id string) (*Batch, error) {
+	var batch Batch
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/batches/"+url.PathEscape(id), nil, &batch, nil); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
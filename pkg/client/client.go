@@ -0,0 +1,268 @@
+// Package client is a Go client for the RunBin API.
+//
+//	c := client.New("https://runbin.example.com", client.WithAPIKey(key))
+//	created, err := c.Submit(ctx, &client.SubmitRequest{Code: code, Language: "c++20", Run: true})
+//	...
+//	paste, err := c.Wait(ctx, created.ID, 0)
+//
+// Requests rejected by rate limiting are retried after the delay the
+// server asks for, and idempotent requests are also retried on server
+// errors, with exponential backoff. The browser sign-in endpoints are not
+// covered.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	maxBackoff        = 30 * time.Second
+)
+
+// Client calls a RunBin server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates every request with key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry, which doubles on every further one. Zero retries
+// disables retrying.
+func WithRetries(max int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max
+		c.backoff = backoff
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://runbin.example.com".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		http:       http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CallOption adds credentials for a single paste to a request.
+type CallOption func(*http.Request)
+
+// ManagementToken authorizes changes to a paste, and reading it if it is
+// private, with the token returned when it was created.
+func ManagementToken(token string) CallOption {
+	return func(r *http.Request) { r.Header.Set("X-Management-Token", token) }
+}
+
+// Password unlocks a password-protected paste.
+func Password(password string) CallOption {
+	return func(r *http.Request) { r.Header.Set("X-Paste-Password", password) }
+}
+
+// Error is an error response from the server.
+type Error struct {
+	StatusCode int
+	// Machine-readable error code, e.g. "not_found" or "rate_limited"
+	Code    string
+	Message string
+	// Delay the server asked for before trying again, if any
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("runbin: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("runbin: %s (%s)", e.Message, e.Code)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request describes one API call.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	contentType string
+	body        []byte
+	opts        []CallOption
+}
+
+// doJSON sends in as the JSON body, if it is not nil, and decodes the
+// response into out, if it is not nil.
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out any, opts []CallOption) error {
+	req := &request{method: method, path: path, opts: opts}
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		req.body = body
+		req.contentType = "application/json"
+	}
+	return c.decode(ctx, req, out, nil)
+}
+
+// decode sends req and unwraps the data of the response envelope into out,
+// and its metadata into meta if it is not nil.
+func (c *Client) decode(ctx context.Context, req *request, out any, meta *responseMeta) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	body := envelope{Data: out, Meta: meta}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("runbin: decode response of %s %s: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// do sends req, retrying as described in the package documentation. On
+// success the caller must close the response body.
+func (c *Client) do(ctx context.Context, req *request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.maxRetries || !c.retryable(req, err) {
+			return nil, err
+		}
+
+		delay := c.backoff << attempt
+		delay = delay/2 + rand.N(delay/2+1)
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			delay = apiErr.RetryAfter
+		}
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a failed request may be sent again. A rate
+// limited request was never processed, so it is safe to retry unless the
+// daily quota is used up, which frees up only at midnight. Other failures
+// may have happened after the server acted on the request, so only
+// requests that can be repeated without effect are retried.
+func (c *Client) retryable(req *request, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			return apiErr.Code != "quota_exceeded"
+		}
+		if apiErr.StatusCode < 500 {
+			return false
+		}
+	}
+	switch req.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// send performs a single attempt of req and turns an error response into
+// an *Error.
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	for _, opt := range req.opts {
+		opt(httpReq)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	var envelope struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&envelope) == nil {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+	}
+	return nil, apiErr
+}
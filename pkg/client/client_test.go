@@ -0,0 +1,287 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"runbin/internal/config"
+	"runbin/internal/controller"
+	"runbin/internal/middleware"
+	"runbin/internal/model"
+	"runbin/internal/notify"
+	"runbin/internal/repository"
+	"runbin/internal/router"
+	"runbin/pkg/client"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testServer is the API with memory storage and no worker. finish plays the
+// worker for a paste's initial run.
+type testServer struct {
+	*httptest.Server
+	store *repository.MemoryPasteStore
+}
+
+func newTestServer(t *testing.T, rateLimit *config.RateLimitConfig, wrap func(http.Handler) http.Handler) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.ApiConfig{
+		App: config.AppConfig{MaxWait: 5},
+		Submission: config.SubmissionConfig{
+			MaxCodeSize:  65536,
+			MaxStdinSize: 65536,
+			MaxBodySize:  1 << 20,
+			MaxBatchSize: 10,
+		},
+	}
+	store := repository.NewMemoryPasteStore()
+	hub := notify.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go store.WatchPastes(ctx, hub.Publish)
+
+	handlers := router.Handlers{
+		Paste:        controller.NewPasteHandler(store, hub, cfg),
+		User:         controller.NewUserHandler(store, store),
+		Authenticate: middleware.Authenticate(store),
+		MaxBodySize:  middleware.MaxBodySize(int64(cfg.Submission.MaxBodySize)),
+	}
+	if rateLimit != nil {
+		cfg.RateLimit = *rateLimit
+		handlers.RateLimit = middleware.RateLimit(repository.NewMemoryRateLimitStore(), &cfg.RateLimit)
+	}
+	engine := gin.New()
+	router.SetupRoutes(engine, handlers)
+
+	var h http.Handler = engine
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, store: store}
+}
+
+func (s *testServer) finish(t *testing.T, id, stdout string) {
+	t.Helper()
+	e, ok := s.store.GetExecution(id)
+	if !ok {
+		t.Errorf("no initial run for paste %s", id)
+		return
+	}
+	done := *e
+	exitCode := 0
+	done.Status = model.StatusCompleted
+	done.Stdout = stdout
+	done.ExitCode = &exitCode
+	if err := s.store.UpdateExecution(&done); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubmitGetWait(t *testing.T) {
+	srv := newTestServer(t, nil, nil)
+	c := client.New(srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	created, err := c.Submit(ctx, &client.SubmitRequest{
+		Code:     "int main() {}",
+		Language: "c++20",
+		Stdin:    "1 2",
+		Run:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.ManagementToken == "" {
+		t.Fatalf("Submit = %+v, want an ID and a management token", created)
+	}
+
+	paste, err := c.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paste.Status != client.StatusPending || paste.Code != "int main() {}" || paste.Stdin != "1 2" {
+		t.Fatalf("Get = %+v, want the pending paste as submitted", paste)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { srv.finish(t, created.ID, "3\n") })
+	paste, err = c.Wait(ctx, created.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paste.Status != client.StatusCompleted || paste.Result.Stdout != "3\n" {
+		t.Fatalf("Wait = %+v, want the completed paste", paste)
+	}
+	if paste.Result.ExitCode == nil || *paste.Result.ExitCode != 0 {
+		t.Errorf("Wait exit code = %v, want 0", paste.Result.ExitCode)
+	}
+	if paste.LatestRun == nil || paste.LatestRun.Status != client.StatusCompleted {
+		t.Errorf("Wait latest run = %+v, want the completed initial run", paste.LatestRun)
+	}
+
+	_, err = c.Get(ctx, "no-such-paste")
+	if !client.IsNotFound(err) {
+		t.Errorf("Get of a missing paste = %v, want not found", err)
+	}
+}
+
+func TestRawAndFork(t *testing.T) {
+	srv := newTestServer(t, nil, nil)
+	c := client.New(srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	created, err := c.Submit(ctx, &client.SubmitRequest{Code: "int main() {}", Language: "c++20", Run: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.finish(t, created.ID, "out\n")
+
+	code, err := c.Raw(ctx, created.ID, "")
+	if err != nil || code != "int main() {}" {
+		t.Errorf("Raw = %q, %v, want the source", code, err)
+	}
+	stdout, err := c.Raw(ctx, created.ID, "stdout")
+	if err != nil || stdout != "out\n" {
+		t.Errorf("Raw stdout = %q, %v, want the output", stdout, err)
+	}
+
+	newCode := "int main() { return 0; }"
+	fork, err := c.Fork(ctx, created.ID, &client.ForkRequest{Code: &newCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forked, err := c.Get(ctx, fork.ID, client.ManagementToken(fork.ManagementToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forked.ParentID != created.ID || forked.Code != newCode || forked.Language != "c++20" || forked.Revision != 2 {
+		t.Errorf("forked paste = %+v, want revision 2 of %s with the new code", forked, created.ID)
+	}
+
+	history, err := c.History(ctx, fork.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ID != created.ID || history[1].ID != fork.ID {
+		t.Errorf("History = %d pastes, want the original and the fork", len(history))
+	}
+}
+
+func TestRetryAfterRateLimit(t *testing.T) {
+	// One anonymous submission per second
+	srv := newTestServer(t, &config.RateLimitConfig{
+		Enabled: true,
+		IP:      config.BucketConfig{Rate: 1, Burst: 1},
+		Key:     config.BucketConfig{Rate: 1, Burst: 1},
+	}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := &client.SubmitRequest{Code: "int main() {}", Language: "c++20"}
+
+	if _, err := client.New(srv.URL).Submit(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	noRetry := client.New(srv.URL, client.WithRetries(0, 0))
+	_, err := noRetry.Submit(ctx, req)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != time.Second {
+		t.Fatalf("Submit without retries = %v, want 429 with Retry-After of a second", err)
+	}
+
+	start := time.Now()
+	if _, err := client.New(srv.URL).Submit(ctx, req); err != nil {
+		t.Fatalf("Submit with retries = %v, want success once the bucket refills", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Submit retried after %v, want it to wait for Retry-After", elapsed)
+	}
+}
+
+// flaky fails the first attempt of every request with 503.
+type flaky struct {
+	next http.Handler
+
+	mu       sync.Mutex
+	seen     map[string]bool
+	attempts map[string]int
+}
+
+func newFlaky(next http.Handler) *flaky {
+	return &flaky{next: next, seen: make(map[string]bool), attempts: make(map[string]int)}
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	f.mu.Lock()
+	f.attempts[key]++
+	failed := f.seen[key]
+	f.seen[key] = true
+	f.mu.Unlock()
+
+	if !failed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":{"code":"internal_error","message":"Unavailable"}}`))
+		return
+	}
+	f.next.ServeHTTP(w, r)
+}
+
+func (f *flaky) count(method, path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.attempts[method+" "+path]
+}
+
+func TestRetryServerErrorsOnlyWhenIdempotent(t *testing.T) {
+	var f *flaky
+	srv := newTestServer(t, nil, func(h http.Handler) http.Handler {
+		f = newFlaky(h)
+		return f
+	})
+	c := client.New(srv.URL, client.WithRetries(3, time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req := &client.SubmitRequest{Code: "int main() {}", Language: "c++20"}
+	_, err := c.Submit(ctx, req)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Submit = %v, want the 503 without a retry", err)
+	}
+	if n := f.count(http.MethodPost, "/api/v1/pastes"); n != 1 {
+		t.Errorf("Submit was sent %d times, want 1", n)
+	}
+
+	// The second attempt of a POST goes through
+	created, err := c.Submit(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/api/v1/pastes/" + created.ID
+	if _, err := c.Get(ctx, created.ID); err != nil {
+		t.Fatalf("Get = %v, want success after a retry", err)
+	}
+	if n := f.count(http.MethodGet, path); n != 2 {
+		t.Errorf("Get was sent %d times, want 2", n)
+	}
+
+	if err := c.Delete(ctx, created.ID, client.ManagementToken(created.ManagementToken)); err != nil {
+		t.Fatalf("Delete = %v, want success after a retry", err)
+	}
+	if n := f.count(http.MethodDelete, path); n != 2 {
+		t.Errorf("Delete was sent %d times, want 2", n)
+	}
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Submit creates a paste, and queues its code to run if req.Run is set.
+func (c *Client) Submit(ctx context.Context, req *SubmitRequest) (*Created, error) {
+	var created Created
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/pastes", req, &created, nil); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Upload creates a paste the way terminal uploads do and returns its URL.
+func (c *Client) Upload(ctx context.Context, code string, opts *UploadOptions) (*Uploaded, error) {
+	query := url.Values{}
+	if opts != nil {
+		set := func(key, value string) {
+			if value != "" {
+				query.Set(key, value)
+			}
+		}
+		set("lang", opts.Language)
+		set("name", opts.Name)
+		set("stdin", opts.Stdin)
+		set("expires_in", opts.ExpiresIn)
+		set("visibility", string(opts.Visibility))
+		if opts.Run {
+			query.Set("run", "1")
+		}
+	}
+
+	resp, err := c.do(ctx, &request{
+		method:      http.MethodPost,
+		path:        "/",
+		query:       query,
+		contentType: "text/plain; charset=utf-8",
+		body:        []byte(code),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Uploaded{
+		URL:             string(bytes.TrimSpace(body)),
+		ManagementToken: resp.Header.Get("X-Management-Token"),
+	}, nil
+}
+
+// List returns one page of public pastes, newest first. Pass the returned
+// NextCursor in opts to get the next page.
+func (c *Client) List(ctx context.Context, opts *ListOptions) (*PasteList, error) {
+	req := &request{method: http.MethodGet, path: "/api/v1/pastes"}
+	if opts != nil {
+		req.query = opts.values()
+	}
+	var list PasteList
+	var meta responseMeta
+	if err := c.decode(ctx, req, &list.Pastes, &meta); err != nil {
+		return nil, err
+	}
+	list.NextCursor = meta.NextCursor
+	return &list, nil
+}
+
+// Get returns a paste with its most recent run. Reading a finished
+// burn-after-reading paste burns it.
+func (c *Client) Get(ctx context.Context, id string, opts ...CallOption) (*Paste, error) {
+	return c.get(ctx, id, 0, opts)
+}
+
+// get returns a paste, first waiting up to wait for its result.
+func (c *Client) get(ctx context.Context, id string, wait time.Duration, opts []CallOption) (*Paste, error) {
+	req := &request{method: http.MethodGet, path: pastePath(id), opts: opts}
+	if wait > 0 {
+		req.query = url.Values{"wait": {wait.String()}}
+	}
+	var paste Paste
+	if err := c.decode(ctx, req, &paste, nil); err != nil {
+		return nil, err
+	}
+	return &paste, nil
+}
+
+// Update changes the expiry or visibility of a paste. It needs the paste's
+// management token or an API key of its owner.
+func (c *Client) Update(ctx context.Context, id string, req *PatchRequest, opts ...CallOption) (*Paste, error) {
+	var paste Paste
+	if err := c.doJSON(ctx, http.MethodPatch, pastePath(id), req, &paste, opts); err != nil {
+		return nil, err
+	}
+	return &paste, nil
+}
+
+// Delete removes a paste. It needs the paste's management token or an API
+// key of its owner.
+func (c *Client) Delete(ctx context.Context, id string, opts ...CallOption) error {
+	return c.doJSON(ctx, http.MethodDelete, pastePath(id), nil, nil, opts)
+}
+
+// Fork creates a new revision of a paste. Nil fields of req are taken from
+// the parent.
+func (c *Client) Fork(ctx context.Context, id string, req *ForkRequest, opts ...CallOption) (*Created, error) {
+	if req == nil {
+		req = &ForkRequest{}
+	}
+	var created Created
+	if err := c.doJSON(ctx, http.MethodPost, pastePath(id)+"/fork", req, &created, opts); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// History returns the lineage of a paste, from the original down to id.
+func (c *Client) History(ctx context.Context, id string, opts ...CallOption) ([]*Paste, error) {
+	var history []*Paste
+	if err := c.doJSON(ctx, http.MethodGet, pastePath(id)+"/history", nil, &history, opts); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Raw returns the source of a paste, or one of "stdout", "stderr" and
+// "compile_log" if stream is not empty.
+func (c *Client) Raw(ctx context.Context, id, stream string, opts ...CallOption) (string, error) {
+	path := pastePath(id) + "/raw"
+	if stream != "" {
+		path += "/" + url.PathEscape(stream)
+	}
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: path, opts: opts})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// Archive returns a zip archive of a paste's source and all outputs. The
+// caller must close it.
+func (c *Client) Archive(ctx context.Context, id string, opts ...CallOption) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: pastePath(id) + "/archive", opts: opts})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Run queues another run of a paste's code with new stdin or limits.
+func (c *Client) Run(ctx context.Context, id string, req *RunRequest, opts ...CallOption) (*RunCreated, error) {
+	if req == nil {
+		req = &RunRequest{}
+	}
+	var created RunCreated
+	if err := c.doJSON(ctx, http.MethodPost, pastePath(id)+"/runs", req, &created, opts); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Runs returns all runs of a paste, newest first.
+func (c *Client) Runs(ctx context.Context, id string, opts ...CallOption) ([]*Run, error) {
+	var runs []*Run
+	if err := c.doJSON(ctx, http.MethodGet, pastePath(id)+"/runs", nil, &runs, opts); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetRun returns one run of a paste.
+func (c *Client) GetRun(ctx context.Context, id, runID string, opts ...CallOption) (*Run, error) {
+	var run Run
+	path := fmt.Sprintf("%s/runs/%s", pastePath(id), url.PathEscape(runID))
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &run, opts); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Languages returns the supported languages.
+func (c *Client) Languages(ctx context.Context) ([]Language, error) {
+	var languages []Language
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/languages", nil, &languages, nil); err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+func pastePath(id string) string {
+	return "/api/v1/pastes/" + url.PathEscape(id)
+}
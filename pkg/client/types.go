@@ -0,0 +1,323 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The types below mirror the JSON of the /api/v1 requests and responses.
+// They are defined here rather than taken from the server, whose internal
+// types may change without notice.
+
+type PasteStatus string
+
+const (
+	StatusPending             PasteStatus = "pending"
+	StatusRunning             PasteStatus = "running"
+	StatusCompileError        PasteStatus = "compile error"
+	StatusRuntimeError        PasteStatus = "runtime error"
+	StatusTimeLimitExceed     PasteStatus = "time limit exceeded"
+	StatusMemoryLimitExceed   PasteStatus = "memory limit exceeded"
+	StatusOutputLimitExceed   PasteStatus = "output limit exceeded"
+	StatusResourceLimitExceed PasteStatus = "resource limit exceeded"
+	StatusUnknownError        PasteStatus = "unknown error"
+	StatusCompleted           PasteStatus = "completed"
+)
+
+// IsTerminal reports whether no further change to the result is expected.
+func (s PasteStatus) IsTerminal() bool {
+	return s != StatusPending && s != StatusRunning
+}
+
+type Visibility string
+
+const (
+	// VisibilityPublic pastes can be read by anyone and appear in listings
+	VisibilityPublic Visibility = "public"
+	// VisibilityUnlisted pastes can be read by anyone with the ID
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPrivate pastes can only be read by their owner
+	VisibilityPrivate Visibility = "private"
+	// VisibilityPassword pastes can be read by anyone with the ID and password
+	VisibilityPassword Visibility = "password"
+)
+
+type Scope string
+
+const (
+	// ScopeRead allows reading pastes and runs
+	ScopeRead Scope = "read"
+	// ScopeSubmit allows creating, running and managing pastes
+	ScopeSubmit Scope = "submit"
+	// ScopeAdmin allows everything, including managing other users
+	ScopeAdmin Scope = "admin"
+)
+
+type SubmitRequest struct {
+	Code             string     `json:"code"`
+	Language         string     `json:"language"`
+	Run              bool       `json:"run"`
+	Stdin            string     `json:"stdin,omitempty"`
+	BackEnd          string     `json:"backend,omitempty"`
+	ExpiresIn        string     `json:"expires_in,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	BurnAfterReading bool       `json:"burn_after_reading,omitempty"`
+	Visibility       Visibility `json:"visibility,omitempty"`
+	Password         string     `json:"password,omitempty"`
+	// Receives a signed POST once the run is finished; requires Run
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+type BatchRequest struct {
+	Items []SubmitRequest `json:"items"`
+}
+
+// ForkRequest creates a new revision of a paste. Nil fields are inherited
+// from the parent.
+type ForkRequest struct {
+	Code     *string `json:"code,omitempty"`
+	Language *string `json:"language,omitempty"`
+	Stdin    *string `json:"stdin,omitempty"`
+	Run      bool    `json:"run"`
+}
+
+// RunRequest runs the stored code of a paste again. Zero limits fall back
+// to the worker's defaults; larger limits are capped by them.
+type RunRequest struct {
+	Stdin       string  `json:"stdin"`
+	TimeLimit   float32 `json:"time_limit,omitempty"`
+	MemoryLimit int     `json:"memory_limit,omitempty"`
+}
+
+// PatchRequest changes the settings of a paste. Nil fields are kept.
+type PatchRequest struct {
+	ExpiresIn  string      `json:"expires_in,omitempty"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+	Visibility *Visibility `json:"visibility,omitempty"`
+	Password   *string     `json:"password,omitempty"`
+}
+
+type KeyRequest struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+type UserRequest struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes,omitempty"`
+}
+
+// Result is the outcome of running a paste's code.
+type Result struct {
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated"`
+	StderrTruncated bool   `json:"stderr_truncated"`
+	CompileLog      string `json:"compile_log"`
+	ExitCode        *int   `json:"exit_code,omitempty"`
+	ExecutionTimeMs int    `json:"execution_time_ms"`
+	MemoryUsageKb   int    `json:"memory_usage_kb"`
+	CacheHit        bool   `json:"cache_hit"`
+	Toolchain       string `json:"toolchain"`
+	BackEnd         string `json:"backend"`
+}
+
+type Paste struct {
+	ID               string      `json:"id"`
+	URL              string      `json:"url"`
+	Code             string      `json:"code"`
+	Language         string      `json:"language"`
+	Stdin            string      `json:"stdin"`
+	Status           PasteStatus `json:"status"`
+	Result           Result      `json:"result"`
+	Visibility       Visibility  `json:"visibility"`
+	BurnAfterReading bool        `json:"burn_after_reading"`
+	OwnerID          string      `json:"owner_id,omitempty"`
+	ParentID         string      `json:"parent_id,omitempty"`
+	Revision         int         `json:"revision"`
+	// Paste whose result was reused instead of running the code again
+	CachedFrom string     `json:"cached_from,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	// Most recent run, only included when a single paste is requested
+	LatestRun *Run `json:"latest_run,omitempty"`
+}
+
+// Run is one execution of a paste's code.
+type Run struct {
+	ID          string      `json:"id"`
+	PasteID     string      `json:"paste_id"`
+	URL         string      `json:"url"`
+	Stdin       string      `json:"stdin"`
+	TimeLimit   float32     `json:"time_limit"`
+	MemoryLimit int         `json:"memory_limit"`
+	Status      PasteStatus `json:"status"`
+	Result      Result      `json:"result"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Created is returned when a paste is submitted or forked.
+type Created struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Token to manage the paste with; it is only ever returned here
+	ManagementToken string `json:"management_token"`
+	CachedFrom      string `json:"cached_from,omitempty"`
+}
+
+// RunCreated is returned when another run of a paste is queued.
+type RunCreated struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// BatchCreated is returned when a batch is submitted. Its items are in the
+// order they were submitted.
+type BatchCreated struct {
+	ID    string     `json:"id"`
+	URL   string     `json:"url"`
+	Items []*Created `json:"items"`
+}
+
+// Batch is the progress of the pastes submitted together in a batch.
+type Batch struct {
+	ID        string        `json:"id"`
+	URL       string        `json:"url"`
+	Progress  BatchProgress `json:"progress"`
+	Items     []*BatchItem  `json:"items"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// BatchProgress counts the items of a batch by the state of their run.
+type BatchProgress struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	Running  int `json:"running"`
+	Finished int `json:"finished"`
+	// Whether every item is finished
+	Done bool `json:"done"`
+}
+
+type BatchItem struct {
+	ID       string      `json:"id"`
+	URL      string      `json:"url"`
+	Language string      `json:"language"`
+	Status   PasteStatus `json:"status"`
+	ExitCode *int        `json:"exit_code,omitempty"`
+}
+
+type Language struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+}
+
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreatedKey is a new API key. The key itself is only ever returned here.
+type CreatedKey struct {
+	Key    APIKey `json:"key"`
+	APIKey string `json:"api_key"`
+}
+
+// CreatedUser is a new user with its first API key.
+type CreatedUser struct {
+	User   User   `json:"user"`
+	Key    APIKey `json:"key"`
+	APIKey string `json:"api_key"`
+}
+
+// Me is the authenticated user and the key used for the request.
+type Me struct {
+	User User   `json:"user"`
+	Key  APIKey `json:"key"`
+}
+
+// envelope wraps every successful response of /api/v1.
+type envelope struct {
+	Data any           `json:"data"`
+	Meta *responseMeta `json:"meta,omitempty"`
+}
+
+type responseMeta struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PasteList is one page of a listing. NextCursor is empty on the last page.
+type PasteList struct {
+	Pastes     []*Paste
+	NextCursor string
+}
+
+// ListOptions filters a listing. Zero fields are left out.
+type ListOptions struct {
+	Language string
+	Status   PasteStatus
+	// User ID, or "me" for the authenticated user
+	Owner         string
+	BackEnd       string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Full-text search over the code
+	Query  string
+	Cursor string
+	Limit  int
+}
+
+func (o *ListOptions) values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("language", o.Language)
+	set("status", string(o.Status))
+	set("owner", o.Owner)
+	set("backend", o.BackEnd)
+	if !o.CreatedAfter.IsZero() {
+		v.Set("created_after", o.CreatedAfter.Format(time.RFC3339))
+	}
+	if !o.CreatedBefore.IsZero() {
+		v.Set("created_before", o.CreatedBefore.Format(time.RFC3339))
+	}
+	set("q", o.Query)
+	set("cursor", o.Cursor)
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return v
+}
+
+// UploadOptions are the settings of a pastebin-style upload. Without a
+// language, it is inferred from the extension of Name.
+type UploadOptions struct {
+	Language   string
+	Name       string
+	Stdin      string
+	Run        bool
+	ExpiresIn  string
+	Visibility Visibility
+}
+
+// Uploaded is returned by a pastebin-style upload.
+type Uploaded struct {
+	URL             string
+	ManagementToken string
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateUser creates a user with a first API key. It needs an admin key.
+func (c *Client) CreateUser(ctx context.Context, req *UserRequest) (*CreatedUser, error) {
+	var created CreatedUser
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/users", req, &created, nil); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Me returns the authenticated user and the key used for the request.
+func (c *Client) Me(ctx context.Context) (*Me, error) {
+	var me Me
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/users/me", nil, &me, nil); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+// MyPastes returns the pastes of the authenticated user, newest first.
+func (c *Client) MyPastes(ctx context.Context) ([]*Paste, error) {
+	var pastes []*Paste
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/users/me/pastes", nil, &pastes, nil); err != nil {
+		return nil, err
+	}
+	return pastes, nil
+}
+
+// Keys returns the API keys of the authenticated user, including revoked
+// ones.
+func (c *Client) Keys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/keys", nil, &keys, nil); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateKey creates another API key for the authenticated user.
+func (c *Client) CreateKey(ctx context.Context, req *KeyRequest) (*CreatedKey, error) {
+	var created CreatedKey
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/keys", req, &created, nil); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// RevokeKey revokes an API key.
+func (c *Client) RevokeKey(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/v1/keys/"+url.PathEscape(id), nil, nil, nil)
+}
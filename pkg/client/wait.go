@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultPollInterval is used by Wait and WaitRun when no interval is given.
+const DefaultPollInterval = time.Second
+
+// How long each request of Wait asks the server to hold on to it
+const longPollWait = 30 * time.Second
+
+// Wait polls a paste until its status is terminal and returns it. Each
+// request waits on the server for the result, so interval only matters
+// between requests that time out. Use a context deadline to bound the wait.
+// A zero interval means DefaultPollInterval.
+func (c *Client) Wait(ctx context.Context, id string, interval time.Duration, opts ...CallOption) (*Paste, error) {
+	return poll(ctx, interval, func() (*Paste, bool, error) {
+		paste, err := c.get(ctx, id, longPollWait, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		return paste, paste.Status.IsTerminal(), nil
+	})
+}
+
+// WaitRun polls a run of a paste until its status is terminal and returns
+// it.
+func (c *Client) WaitRun(ctx context.Context, id, runID string, interval time.Duration, opts ...CallOption) (*Run, error) {
+	return poll(ctx, interval, func() (*Run, bool, error) {
+		run, err := c.GetRun(ctx, id, runID, opts...)
+		if err != nil {
+			return nil, false, err
+		}
+		return run, run.Status.IsTerminal(), nil
+	})
+}
+
+// poll calls check every interval until it reports done or fails.
+func poll[T any](ctx context.Context, interval time.Duration, check func() (T, bool, error)) (T, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, done, err := check()
+		if err != nil || done {
+			return result, err
+		}
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}